@@ -0,0 +1,91 @@
+package dnsstamps
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Canonical returns a normalized copy of the stamp: IP addresses are in
+// their canonical form, the server address includes its port, the provider
+// name is lowercase and has no default port, hashes are sorted, and
+// duplicate hashes and bootstrap IP addresses are removed. The order of the
+// bootstrap IP addresses is kept.
+func (stamp *ServerStamp) Canonical() ServerStamp {
+	canonical := ServerStamp{
+		Proto:         stamp.Proto,
+		Props:         stamp.Props,
+		ServerAddrStr: stamp.ServerAddrStr,
+		ProviderName:  stamp.ProviderName,
+		Path:          stamp.Path,
+	}
+	if len(stamp.ServerPk) > 0 {
+		canonical.ServerPk = append([]uint8{}, stamp.ServerPk...)
+	}
+	if stamp.Payload != nil {
+		canonical.Payload = append([]byte{}, stamp.Payload...)
+	}
+
+	// Addresses without an IP address, such as ":8853", are only given the
+	// default port if they have none, like invalid addresses.
+	if addrPort, err := stamp.AddrPort(); err == nil {
+		canonical.ServerAddrStr = addrPort.String()
+	} else if stamp.ServerAddrStr != "" && portIndex(stamp.ServerAddrStr) < 0 {
+		if codec, ok := LookupCodec(stamp.Proto); ok {
+			canonical.ServerAddrStr += ":" + strconv.Itoa(codec.DefaultPort)
+		}
+	}
+
+	if stamp.ProviderName != "" {
+		providerName := strings.ToLower(stamp.ProviderName)
+		if codec, ok := LookupCodec(stamp.Proto); ok {
+			providerName = strings.TrimSuffix(providerName, ":"+strconv.Itoa(codec.DefaultPort))
+		}
+		canonical.ProviderName = providerName
+	}
+
+	for _, hash := range stamp.Hashes {
+		canonical.Hashes = append(canonical.Hashes, append([]uint8{}, hash...))
+	}
+	sort.Slice(canonical.Hashes, func(i, j int) bool {
+		return bytes.Compare(canonical.Hashes[i], canonical.Hashes[j]) < 0
+	})
+	for i := 1; i < len(canonical.Hashes); i++ {
+		if bytes.Equal(canonical.Hashes[i-1], canonical.Hashes[i]) {
+			canonical.Hashes = append(canonical.Hashes[:i], canonical.Hashes[i+1:]...)
+			i--
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, bootstrapIP := range stamp.BootstrapIPs {
+		if normalized, err := normalizeBootstrapIP(bootstrapIP); err == nil {
+			bootstrapIP = normalized
+		}
+		if !seen[bootstrapIP] {
+			canonical.BootstrapIPs = append(canonical.BootstrapIPs, bootstrapIP)
+			seen[bootstrapIP] = true
+		}
+	}
+	return canonical
+}
+
+// Equal returns true if both stamps have the same canonical form.
+func (stamp *ServerStamp) Equal(other *ServerStamp) bool {
+	a, b := stamp.Canonical(), other.Canonical()
+	return reflect.DeepEqual(&a, &b)
+}
+
+// Fingerprint returns the SHA-256 digest of the encoding of the canonical
+// form of the stamp. Equal stamps have the same fingerprint.
+func (stamp *ServerStamp) Fingerprint() ([sha256.Size]byte, error) {
+	canonical := stamp.Canonical()
+	bin, err := canonical.bytes()
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(bin), nil
+}
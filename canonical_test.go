@@ -0,0 +1,79 @@
+package dnsstamps
+
+import (
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	hash2 := make([]byte, 32)
+	stamp := ServerStamp{
+		Proto:         StampProtoTypeDoH,
+		ServerAddrStr: "[2001:0db8:0:0::1]",
+		ProviderName:  "DNS.Example.com:443",
+		Path:          "/dns-query",
+		Hashes:        [][]uint8{pk1, hash2, pk1},
+		BootstrapIPs:  []string{"2001:db8:0::53", "192.0.2.1", "2001:db8::53"},
+	}
+	canonical := stamp.Canonical()
+	if canonical.ServerAddrStr != "[2001:db8::1]:443" {
+		t.Errorf("unexpected server address %q", canonical.ServerAddrStr)
+	}
+	if canonical.ProviderName != "dns.example.com" {
+		t.Errorf("unexpected provider name %q", canonical.ProviderName)
+	}
+	if len(canonical.Hashes) != 2 || canonical.Hashes[0][0] != 0 {
+		t.Errorf("expected two sorted hashes, got %x", canonical.Hashes)
+	}
+	if len(canonical.BootstrapIPs) != 2 || canonical.BootstrapIPs[0] != "2001:db8::53" || canonical.BootstrapIPs[1] != "192.0.2.1" {
+		t.Errorf("unexpected bootstrap IPs %v", canonical.BootstrapIPs)
+	}
+	canonical.Hashes[0][0] = 0xff
+	if hash2[0] != 0 {
+		t.Error("expected the canonical form not to share hashes with the original")
+	}
+}
+
+func TestEqualAndFingerprint(t *testing.T) {
+	a := ServerStamp{
+		Proto:         StampProtoTypeTLS,
+		ServerAddrStr: "[2606:4700:4700:0:0:0:0:1111]",
+		ProviderName:  "cloudflare-dns.com",
+		Hashes:        [][]uint8{pk1, make([]byte, 32)},
+	}
+	parsed, err := NewServerStampFromString(a.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := ServerStamp{
+		Proto:         StampProtoTypeTLS,
+		ServerAddrStr: "[2606:4700:4700::1111]:853",
+		ProviderName:  "cloudflare-dns.com",
+		Hashes:        [][]uint8{make([]byte, 32), pk1},
+	}
+	for _, other := range []ServerStamp{parsed, b} {
+		if !a.Equal(&other) {
+			t.Errorf("expected %+v to be equal to %+v", a, other)
+		}
+		fa, err := a.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fb, err := other.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fa != fb {
+			t.Errorf("expected identical fingerprints, got %x and %x", fa, fb)
+		}
+	}
+
+	b.ServerAddrStr = "[2606:4700:4700::1111]:8853"
+	if a.Equal(&b) {
+		t.Error("expected stamps with different ports to differ")
+	}
+	fa, _ := a.Fingerprint()
+	fb, _ := b.Fingerprint()
+	if fa == fb {
+		t.Error("expected different fingerprints")
+	}
+}
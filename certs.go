@@ -0,0 +1,82 @@
+package dnsstamps
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CertHash returns the hash of a certificate, as stored in the Hashes of a
+// stamp: the SHA-256 digest of its TBS (to-be-signed) section.
+func CertHash(cert *x509.Certificate) []uint8 {
+	hash := sha256.Sum256(cert.RawTBSCertificate)
+	return hash[:]
+}
+
+// HashesFromChain returns the hashes of the certificates of a chain, in the
+// same order.
+func HashesFromChain(chain []*x509.Certificate) [][]uint8 {
+	hashes := make([][]uint8, 0, len(chain))
+	for _, cert := range chain {
+		hashes = append(hashes, CertHash(cert))
+	}
+	return hashes
+}
+
+// MatchesChain returns the index of the first certificate of the chain
+// whose hash is one of the hashes of the stamp. The index is -1 and the
+// result false if there is no match, including when the stamp has no
+// hashes.
+func (stamp *ServerStamp) MatchesChain(chain []*x509.Certificate) (int, bool) {
+	for i, cert := range chain {
+		certHash := CertHash(cert)
+		for _, hash := range stamp.Hashes {
+			if bytes.Equal(hash, certHash) {
+				return i, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// ParseCertificatesPEM parses the "CERTIFICATE" blocks of PEM data. Other
+// blocks are ignored.
+func ParseCertificatesPEM(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("No certificates found")
+	}
+	return chain, nil
+}
+
+// LoadCertificatesPEM reads the certificates of a PEM file.
+func LoadCertificatesPEM(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := ParseCertificatesPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return chain, nil
+}
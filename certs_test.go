@@ -0,0 +1,116 @@
+package dnsstamps
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertChain returns a leaf certificate for name, signed by a CA.
+func testCertChain(t *testing.T, name string) []*x509.Certificate {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*x509.Certificate{leaf, ca}
+}
+
+func TestCertHash(t *testing.T) {
+	chain := testCertChain(t, "doh.example.com")
+	expected := sha256.Sum256(chain[0].RawTBSCertificate)
+	if hash := CertHash(chain[0]); !bytes.Equal(hash, expected[:]) {
+		t.Errorf("unexpected hash %x", hash)
+	}
+	hashes := HashesFromChain(chain)
+	if len(hashes) != 2 || !bytes.Equal(hashes[1], CertHash(chain[1])) {
+		t.Errorf("unexpected hashes %x", hashes)
+	}
+}
+
+func TestMatchesChain(t *testing.T) {
+	chain := testCertChain(t, "doh.example.com")
+	stamp := ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "doh.example.com", Path: "/dns-query"}
+	if i, ok := stamp.MatchesChain(chain); ok || i != -1 {
+		t.Errorf("a stamp without hashes matched element %d", i)
+	}
+	stamp.Hashes = [][]uint8{pk1, CertHash(chain[1])}
+	if i, ok := stamp.MatchesChain(chain); !ok || i != 1 {
+		t.Errorf("expected the CA to match, got %d, %v", i, ok)
+	}
+	stamp.Hashes = [][]uint8{pk1}
+	if _, ok := stamp.MatchesChain(chain); ok {
+		t.Error("unexpected match")
+	}
+}
+
+func TestLoadCertificatesPEM(t *testing.T) {
+	chain := testCertChain(t, "doh.example.com")
+	var data []byte
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{0}})...)
+	for _, cert := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	path := filepath.Join(t.TempDir(), "chain.pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCertificatesPEM(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || !loaded[0].Equal(chain[0]) || !loaded[1].Equal(chain[1]) {
+		t.Errorf("unexpected certificates %v", loaded)
+	}
+	if _, err := ParseCertificatesPEM([]byte("not PEM")); err == nil {
+		t.Error("expected an error for data without certificates")
+	}
+}
@@ -0,0 +1,108 @@
+package dnsstamps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RelayChain is a server stamp, reached through one or more relays. Queries
+// are sent to the first relay, that forwards them to the next relay, and so
+// on until the last relay forwards them to the server. Its string form is
+// "sdns://<relay1>/<relay2>/.../<server>".
+//
+// Only ODoH relays can forward queries to other relays. A chain with a
+// single relay is equivalent to a RelayedStamp.
+type RelayChain struct {
+	Relays []ServerStamp
+	Server ServerStamp
+}
+
+// NewRelayChainFromString parses a "sdns://<relay1>/.../<server>" string,
+// and checks that every hop can forward queries to the next one. The short
+// "sdns:" form is also accepted.
+func NewRelayChainFromString(stampStr string) (RelayChain, error) {
+	stamps, err := parseStampChain(stampStr)
+	if err != nil {
+		return RelayChain{}, err
+	}
+	if len(stamps) < 2 {
+		return RelayChain{}, ErrNotRelayedStamp
+	}
+	chain := RelayChain{Relays: stamps[:len(stamps)-1], Server: stamps[len(stamps)-1]}
+	if err := chain.checkHops(); err != nil {
+		return RelayChain{}, err
+	}
+	return chain, nil
+}
+
+// Validate checks every stamp of the chain, and that every hop can forward
+// queries to the next one.
+func (chain *RelayChain) Validate() error {
+	if err := chain.checkHops(); err != nil {
+		return err
+	}
+	for i := range chain.Relays {
+		if err := chain.Relays[i].Validate(); err != nil {
+			return fmt.Errorf("hop %d: %w", i, err)
+		}
+	}
+	if err := chain.Server.Validate(); err != nil {
+		return fmt.Errorf("hop %d: %w", len(chain.Relays), err)
+	}
+	return nil
+}
+
+// Encode returns the "sdns://<relay1>/.../<server>" form of the chain, after
+// checking that every hop can forward queries to the next one, and that
+// every stamp can be encoded. See ServerStamp.Encode().
+func (chain *RelayChain) Encode() (string, error) {
+	if err := chain.checkHops(); err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(chain.Relays)+1)
+	for i, stamp := range append(chain.Relays[:len(chain.Relays):len(chain.Relays)], chain.Server) {
+		stampStr, err := stamp.Encode()
+		if err != nil {
+			return "", fmt.Errorf("hop %d: %w", i, err)
+		}
+		parts = append(parts, strings.TrimPrefix(stampStr, StampScheme))
+	}
+	return StampScheme + strings.Join(parts, "/"), nil
+}
+
+// String returns the "sdns://<relay1>/.../<server>" form of the chain,
+// without checking the hops, or an empty string if a stamp cannot be
+// encoded. See ServerStamp.String().
+func (chain *RelayChain) String() string {
+	parts := make([]string, 0, len(chain.Relays)+1)
+	for _, stamp := range append(chain.Relays[:len(chain.Relays):len(chain.Relays)], chain.Server) {
+		stampStr := stamp.String()
+		if stampStr == "" {
+			return ""
+		}
+		parts = append(parts, strings.TrimPrefix(stampStr, StampScheme))
+	}
+	return StampScheme + strings.Join(parts, "/")
+}
+
+// checkHops checks that the chain has at least one relay, and that every
+// hop can forward queries to the next one.
+func (chain *RelayChain) checkHops() error {
+	if len(chain.Relays) == 0 {
+		return ErrNotRelayedStamp
+	}
+	for i := 0; i < len(chain.Relays)-1; i++ {
+		relay, next := &chain.Relays[i], &chain.Relays[i+1]
+		if !relay.Proto.relay() {
+			return fmt.Errorf("hop %d: %w", i, ErrNotRelay)
+		}
+		if relay.Proto != StampProtoTypeODoHRelay || next.Proto != StampProtoTypeODoHRelay {
+			return fmt.Errorf("hop %d: %w: %s -> %s", i, ErrIncompatibleRelay, relay.Proto.String(), next.Proto.String())
+		}
+	}
+	last := len(chain.Relays) - 1
+	if err := checkRelayPair(&chain.Relays[last], &chain.Server); err != nil {
+		return fmt.Errorf("hop %d: %w", last, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package dnsstamps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRelayChain(t *testing.T) {
+	relay1 := ServerStamp{Proto: StampProtoTypeODoHRelay, ProviderName: "relay1.example.com", Path: "/relay"}
+	relay2 := ServerStamp{Proto: StampProtoTypeODoHRelay, ProviderName: "relay2.example.com", Path: "/relay"}
+	target := ServerStamp{Proto: StampProtoTypeODoHTarget, ProviderName: "target.example.com", Path: "/target"}
+	chain := RelayChain{Relays: []ServerStamp{relay1, relay2}, Server: target}
+
+	stampStr, err := chain.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := strings.Split(stampStr, "/"); len(parts) != 5 {
+		t.Errorf("unexpected chain string %q", stampStr)
+	}
+	parsed, err := NewRelayChainFromString(stampStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Relays) != 2 || parsed.Relays[0].ProviderName != "relay1.example.com" ||
+		parsed.Relays[1].ProviderName != "relay2.example.com" || parsed.Server.ProviderName != "target.example.com" {
+		t.Errorf("unexpected chain %+v", parsed)
+	}
+	if s := parsed.String(); s != stampStr {
+		t.Errorf("re-parsed chain string is %q, but %q expected", s, stampStr)
+	}
+
+	single, err := NewRelayChainFromString(relayedTestStamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(single.Relays) != 1 || single.String() != relayedTestStamp {
+		t.Errorf("unexpected chain %+v", single)
+	}
+	if _, _, err := NewRelayAndServerStampFromString(stampStr); !errors.Is(err, ErrNotRelayedStamp) {
+		t.Errorf("expected %v, got %v", ErrNotRelayedStamp, err)
+	}
+}
+
+func TestRelayChain_Errors(t *testing.T) {
+	oDoHRelay := ServerStamp{Proto: StampProtoTypeODoHRelay, ProviderName: "relay.example.com", Path: "/relay"}
+	dnsCryptRelay := ServerStamp{Proto: StampProtoTypeDNSCryptRelay, ServerAddrStr: "1.1.1.1:443"}
+	target := ServerStamp{Proto: StampProtoTypeODoHTarget, ProviderName: "target.example.com", Path: "/target"}
+
+	tests := []struct {
+		chain RelayChain
+		err   error
+	}{
+		{RelayChain{Server: target}, ErrNotRelayedStamp},
+		{RelayChain{Relays: []ServerStamp{dnsCryptRelay, oDoHRelay}, Server: target}, ErrIncompatibleRelay},
+		{RelayChain{Relays: []ServerStamp{oDoHRelay, dnsCryptRelay}, Server: target}, ErrIncompatibleRelay},
+		{RelayChain{Relays: []ServerStamp{target, oDoHRelay}, Server: target}, ErrNotRelay},
+		{RelayChain{Relays: []ServerStamp{oDoHRelay, oDoHRelay}, Server: oDoHRelay}, ErrRelayedRelay},
+	}
+	for i, test := range tests {
+		if _, err := NewRelayChainFromString(test.chain.String()); !errors.Is(err, test.err) {
+			t.Errorf("chain %d: expected %v, got %v", i, test.err, err)
+		}
+		if _, err := test.chain.Encode(); !errors.Is(err, test.err) {
+			t.Errorf("chain %d: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
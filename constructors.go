@@ -0,0 +1,141 @@
+package dnsstamps
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+)
+
+// NewPlainServerStamp returns a plain DNS stamp. The default port is used if
+// serverAddrStr doesn't include one.
+func NewPlainServerStamp(serverAddrStr string, props ServerInformalProperties) (ServerStamp, error) {
+	return newServerStamp(ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: serverAddrStr, Props: props})
+}
+
+// NewDoHServerStamp returns a DoH stamp. serverAddrStr is the optional IP
+// address of the server, and providerName its host name, with an optional
+// port.
+func NewDoHServerStamp(serverAddrStr, providerName, path string, hashes [][]uint8, props ServerInformalProperties) (ServerStamp, error) {
+	return newServerStamp(ServerStamp{
+		Proto:         StampProtoTypeDoH,
+		ServerAddrStr: serverAddrStr,
+		ProviderName:  providerName,
+		Path:          path,
+		Hashes:        hashes,
+		Props:         props,
+	})
+}
+
+// NewDoTServerStamp returns a DoT stamp. serverAddrStr is the optional IP
+// address of the server, and providerName its host name, with an optional
+// port.
+func NewDoTServerStamp(serverAddrStr, providerName string, hashes [][]uint8, props ServerInformalProperties) (ServerStamp, error) {
+	return newServerStamp(ServerStamp{
+		Proto:         StampProtoTypeTLS,
+		ServerAddrStr: serverAddrStr,
+		ProviderName:  providerName,
+		Hashes:        hashes,
+		Props:         props,
+	})
+}
+
+// NewDoQServerStamp returns a DoQ stamp. serverAddrStr is the optional IP
+// address of the server, and providerName its host name, with an optional
+// port.
+func NewDoQServerStamp(serverAddrStr, providerName string, hashes [][]uint8, props ServerInformalProperties) (ServerStamp, error) {
+	return newServerStamp(ServerStamp{
+		Proto:         StampProtoTypeDoQ,
+		ServerAddrStr: serverAddrStr,
+		ProviderName:  providerName,
+		Hashes:        hashes,
+		Props:         props,
+	})
+}
+
+// NewODoHTargetStamp returns an ODoH target stamp.
+func NewODoHTargetStamp(providerName, path string, props ServerInformalProperties) (ServerStamp, error) {
+	return newServerStamp(ServerStamp{
+		Proto:        StampProtoTypeODoHTarget,
+		ProviderName: providerName,
+		Path:         path,
+		Props:        props,
+	})
+}
+
+// NewDNSCryptRelayStamp returns a DNSCrypt relay stamp. The default port is
+// used if serverAddrStr doesn't include one.
+func NewDNSCryptRelayStamp(serverAddrStr string) (ServerStamp, error) {
+	return newServerStamp(ServerStamp{Proto: StampProtoTypeDNSCryptRelay, ServerAddrStr: serverAddrStr})
+}
+
+// NewODoHRelayStamp returns an ODoH relay stamp. serverAddrStr is the
+// optional IP address of the relay, and providerName its host name, with an
+// optional port.
+func NewODoHRelayStamp(serverAddrStr, providerName, path string, hashes [][]uint8, props ServerInformalProperties) (ServerStamp, error) {
+	return newServerStamp(ServerStamp{
+		Proto:         StampProtoTypeODoHRelay,
+		ServerAddrStr: serverAddrStr,
+		ProviderName:  providerName,
+		Path:          path,
+		Hashes:        hashes,
+		Props:         props,
+	})
+}
+
+// NewServerStampFromURL returns the stamp of a server given as an URL:
+//
+//   - "https://host[:port]/path" for DoH, the path being "/dns-query" if
+//     there is none
+//   - "tls://host[:port]" for DoT
+//   - "quic://host[:port]" for DoQ
+//   - "udp://ip[:port]" for plain DNS, that doesn't support hashes
+//
+// If the host is an IP address, it is also used as the server address.
+func NewServerStampFromURL(rawURL string, props ServerInformalProperties, hashes [][]uint8) (ServerStamp, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ServerStamp{}, err
+	}
+	if u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return ServerStamp{}, fmt.Errorf("Unsupported URL: [%s]", rawURL)
+	}
+	serverAddrStr := ""
+	if _, err := netip.ParseAddr(u.Hostname()); err == nil {
+		serverAddrStr = u.Host
+	}
+	if u.Scheme != "https" && u.Path != "" && u.Path != "/" {
+		return ServerStamp{}, fmt.Errorf("Unexpected path in URL: [%s]", rawURL)
+	}
+
+	switch u.Scheme {
+	case "https":
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/dns-query"
+		}
+		return NewDoHServerStamp(serverAddrStr, u.Host, path, hashes, props)
+	case "tls":
+		return NewDoTServerStamp(serverAddrStr, u.Host, hashes, props)
+	case "quic":
+		return NewDoQServerStamp(serverAddrStr, u.Host, hashes, props)
+	case "udp":
+		if serverAddrStr == "" {
+			return ServerStamp{}, fmt.Errorf("Plain DNS servers require an IP address: [%s]", rawURL)
+		}
+		return newServerStamp(ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: serverAddrStr, Hashes: hashes, Props: props})
+	}
+	return ServerStamp{}, fmt.Errorf("Unsupported URL scheme: [%s]", u.Scheme)
+}
+
+// newServerStamp checks a stamp with Validate(), and returns it as it would
+// be decoded from its encoding, so that the same default port rules apply.
+func newServerStamp(stamp ServerStamp) (ServerStamp, error) {
+	if err := stamp.Validate(); err != nil {
+		return ServerStamp{}, err
+	}
+	bin, err := stamp.AppendBinary(nil)
+	if err != nil {
+		return ServerStamp{}, err
+	}
+	return newServerStampFromBinary(bin, &ParseOptions{})
+}
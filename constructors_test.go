@@ -0,0 +1,114 @@
+package dnsstamps
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConstructors(t *testing.T) {
+	plain, err := NewPlainServerStamp("8.8.8.8", ServerInformalPropertyDNSSEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.ServerAddrStr != "8.8.8.8:53" {
+		t.Errorf("unexpected address %q", plain.ServerAddrStr)
+	}
+
+	doh, err := NewDoHServerStamp("1.1.1.1", "cloudflare-dns.com", "/dns-query", [][]uint8{pk1}, ServerInformalPropertyNoLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := NewServerStampFromString(doh.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doh, parsed) || doh.ServerAddrStr != "1.1.1.1:443" {
+		t.Errorf("constructed %+v, but re-parsed %+v", doh, parsed)
+	}
+
+	dot, err := NewDoTServerStamp("", "dns.google", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doq, err := NewDoQServerStamp("[2001:db8::1]", "dns.example.com:8853", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dot.Proto != StampProtoTypeTLS || doq.ServerAddrStr != "[2001:db8::1]:853" || doq.ProviderName != "dns.example.com:8853" {
+		t.Errorf("unexpected stamps %+v and %+v", dot, doq)
+	}
+
+	target, err := NewODoHTargetStamp("odoh.example.com", "/dns-query", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oDoHRelay, err := NewODoHRelayStamp("", "relay.example.com", "/relay", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CanRelay(oDoHRelay, target) {
+		t.Error("the ODoH relay cannot relay to the target")
+	}
+	dnsCryptRelay, err := NewDNSCryptRelayStamp("192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dnsCryptRelay.ServerAddrStr != "192.0.2.1:443" {
+		t.Errorf("unexpected address %q", dnsCryptRelay.ServerAddrStr)
+	}
+
+	if _, err := NewDoHServerStamp("", "doh.example.com", "dns-query", nil, 0); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("expected %v, got %v", ErrInvalidPath, err)
+	}
+	if _, err := NewPlainServerStamp("dns.example.com", 0); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("expected %v, got %v", ErrInvalidIP, err)
+	}
+}
+
+func TestNewServerStampFromURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected ServerStamp
+	}{
+		{"https://dns.example.com/dns-query", ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com", Path: "/dns-query"}},
+		{"https://dns.example.com:8443", ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com:8443", Path: "/dns-query"}},
+		{"https://1.1.1.1/resolve", ServerStamp{Proto: StampProtoTypeDoH, ServerAddrStr: "1.1.1.1:443", ProviderName: "1.1.1.1", Path: "/resolve"}},
+		{"tls://dns.example.com", ServerStamp{Proto: StampProtoTypeTLS, ProviderName: "dns.example.com"}},
+		{"quic://[2001:db8::1]:8853", ServerStamp{Proto: StampProtoTypeDoQ, ServerAddrStr: "[2001:db8::1]:8853", ProviderName: "[2001:db8::1]:8853"}},
+		{"udp://9.9.9.9:5353", ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: "9.9.9.9:5353"}},
+	}
+	for _, test := range tests {
+		stamp, err := NewServerStampFromURL(test.url, 0, nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.url, err)
+			continue
+		}
+		if !reflect.DeepEqual(stamp, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.url, test.expected, stamp)
+		}
+	}
+
+	stamp, err := NewServerStampFromURL("tls://dns.example.com", ServerInformalPropertyNoLog, [][]uint8{pk1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.Props != ServerInformalPropertyNoLog || len(stamp.Hashes) != 1 {
+		t.Errorf("props and hashes were not kept: %+v", stamp)
+	}
+
+	for _, rawURL := range []string{
+		"ftp://dns.example.com",
+		"udp://dns.example.com",
+		"tls://dns.example.com/path",
+		"https://dns.example.com/dns-query?dns=AAAA",
+		"https://user@dns.example.com/dns-query",
+	} {
+		if _, err := NewServerStampFromURL(rawURL, 0, nil); err == nil {
+			t.Errorf("%s: expected an error", rawURL)
+		}
+	}
+	if _, err := NewServerStampFromURL("udp://9.9.9.9", 0, [][]uint8{pk1}); !errors.Is(err, ErrUnexpectedField) {
+		t.Errorf("expected %v, got %v", ErrUnexpectedField, err)
+	}
+}
@@ -0,0 +1,155 @@
+package dnsstamps
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ChangeKind tells if a field was modified, or if a value was added to or
+// removed from a field.
+type ChangeKind int
+
+const (
+	ChangeModified ChangeKind = iota
+	ChangeAdded
+	ChangeRemoved
+)
+
+// Change is a difference between two stamps. For hashes, bootstrap IP
+// addresses and properties, every added or removed value is a distinct
+// change.
+type Change struct {
+	Kind  ChangeKind
+	Field string
+	Old   string
+	New   string
+}
+
+func (change Change) String() string {
+	switch change.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: + %s", change.Field, change.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: - %s", change.Field, change.Old)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", change.Field, change.Old, change.New)
+	}
+}
+
+type Changes []Change
+
+// String renders the changes, one per line.
+func (changes Changes) String() string {
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+var propNames = []struct {
+	prop ServerInformalProperties
+	name string
+}{
+	{ServerInformalPropertyDNSSEC, "dnssec"},
+	{ServerInformalPropertyNoLog, "nolog"},
+	{ServerInformalPropertyNoFilter, "nofilter"},
+}
+
+// Diff returns the changes required to turn stamp a into stamp b. Both
+// stamps are compared in their canonical form, so that equivalent
+// representations of a field are not reported as changes.
+func Diff(a, b ServerStamp) Changes {
+	a, b = a.Canonical(), b.Canonical()
+	var changes Changes
+	modified := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, Change{Kind: ChangeModified, Field: field, Old: old, New: new})
+		}
+	}
+
+	if a.Proto != b.Proto {
+		changes = append(changes, Change{Kind: ChangeModified, Field: FieldProto, Old: protoName(a.Proto), New: protoName(b.Proto)})
+	}
+	aHost, aPort := splitServerAddr(a.ServerAddrStr)
+	bHost, bPort := splitServerAddr(b.ServerAddrStr)
+	modified(FieldAddress, aHost, bHost)
+	modified(FieldPort, aPort, bPort)
+	modified(FieldPublicKey, hex.EncodeToString(a.ServerPk), hex.EncodeToString(b.ServerPk))
+	changes = append(changes, diffSets(FieldHash, hexStrings(a.Hashes), hexStrings(b.Hashes))...)
+	modified(FieldProviderName, a.ProviderName, b.ProviderName)
+	modified(FieldPath, a.Path, b.Path)
+
+	var aProps, bProps []string
+	for i := 0; i < 64; i++ {
+		prop := ServerInformalProperties(1) << i
+		name := fmt.Sprintf("bit%d", i)
+		for _, propName := range propNames {
+			if propName.prop == prop {
+				name = propName.name
+			}
+		}
+		if a.Props&prop != 0 {
+			aProps = append(aProps, name)
+		}
+		if b.Props&prop != 0 {
+			bProps = append(bProps, name)
+		}
+	}
+	changes = append(changes, diffSets(FieldProps, aProps, bProps)...)
+	changes = append(changes, diffSets(FieldBootstrapIP, a.BootstrapIPs, b.BootstrapIPs)...)
+	if !bytes.Equal(a.Payload, b.Payload) {
+		modified(FieldPayload, hex.EncodeToString(a.Payload), hex.EncodeToString(b.Payload))
+	}
+	return changes
+}
+
+// protoName returns the name of a protocol, or its value for the protocols
+// that have no name.
+func protoName(proto StampProtoType) string {
+	if !proto.supported() {
+		return fmt.Sprintf("0x%02x", uint8(proto))
+	}
+	return proto.String()
+}
+
+func splitServerAddr(serverAddrStr string) (string, string) {
+	if colIndex := portIndex(serverAddrStr); colIndex >= 0 {
+		return serverAddrStr[:colIndex], serverAddrStr[colIndex+1:]
+	}
+	return serverAddrStr, ""
+}
+
+func hexStrings(values [][]uint8) []string {
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		strs = append(strs, hex.EncodeToString(value))
+	}
+	return strs
+}
+
+// diffSets returns the values of a missing from b as removals, followed by
+// the values of b missing from a as additions.
+func diffSets(field string, a, b []string) Changes {
+	var changes Changes
+	inA, inB := make(map[string]bool), make(map[string]bool)
+	for _, value := range a {
+		inA[value] = true
+	}
+	for _, value := range b {
+		inB[value] = true
+	}
+	for _, value := range a {
+		if !inB[value] {
+			changes = append(changes, Change{Kind: ChangeRemoved, Field: field, Old: value})
+		}
+	}
+	for _, value := range b {
+		if !inA[value] {
+			changes = append(changes, Change{Kind: ChangeAdded, Field: field, New: value})
+		}
+	}
+	return changes
+}
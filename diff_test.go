@@ -0,0 +1,68 @@
+package dnsstamps
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	hash2 := make([]byte, 32)
+	a := ServerStamp{
+		Proto:         StampProtoTypeDoH,
+		Props:         ServerInformalPropertyDNSSEC | ServerInformalPropertyNoLog,
+		ServerAddrStr: "1.1.1.1",
+		ProviderName:  "cloudflare-dns.com",
+		Path:          "/dns-query",
+		Hashes:        [][]uint8{pk1},
+		BootstrapIPs:  []string{"1.1.1.1"},
+	}
+	b := a
+	b.Props = ServerInformalPropertyDNSSEC | ServerInformalPropertyNoFilter
+	b.ServerAddrStr = "1.0.0.1:8443"
+	b.Hashes = [][]uint8{hash2}
+	b.BootstrapIPs = []string{"1.1.1.1", "1.0.0.1"}
+
+	changes := Diff(a, b)
+	expected := Changes{
+		{Kind: ChangeModified, Field: FieldAddress, Old: "1.1.1.1", New: "1.0.0.1"},
+		{Kind: ChangeModified, Field: FieldPort, Old: "443", New: "8443"},
+		{Kind: ChangeRemoved, Field: FieldHash, Old: hex.EncodeToString(pk1)},
+		{Kind: ChangeAdded, Field: FieldHash, New: hex.EncodeToString(hash2)},
+		{Kind: ChangeRemoved, Field: FieldProps, Old: "nolog"},
+		{Kind: ChangeAdded, Field: FieldProps, New: "nofilter"},
+		{Kind: ChangeAdded, Field: FieldBootstrapIP, New: "1.0.0.1"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes but got:\n%s", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("change %d: expected %q but got %q", i, expected[i], changes[i])
+		}
+	}
+	if s := changes[0].String(); s != "address: 1.1.1.1 -> 1.0.0.1" {
+		t.Errorf("unexpected rendering %q", s)
+	}
+	if s := changes[6].String(); s != "bootstrap IP: + 1.0.0.1" {
+		t.Errorf("unexpected rendering %q", s)
+	}
+}
+
+func TestDiff_Equivalent(t *testing.T) {
+	a := ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: "[2001:db8:0:0::1]"}
+	b := ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: "[2001:db8::1]:53"}
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Errorf("expected no changes, got:\n%s", changes)
+	}
+}
+
+func TestDiff_UnknownProtocols(t *testing.T) {
+	a := ServerStamp{Proto: 0x10, Payload: []uint8{1, 2, 3}}
+	b := ServerStamp{Proto: 0x11, Payload: []uint8{1, 2, 3}}
+	changes := Diff(a, b)
+	expected := Changes{{Kind: ChangeModified, Field: FieldProto, Old: "0x10", New: "0x11"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, changes)
+	}
+}
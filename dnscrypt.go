@@ -0,0 +1,178 @@
+package dnsstamps
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Crypto constructions of DNSCrypt certificates (es-version).
+const (
+	DNSCryptESVersionXSalsa20Poly1305  = 1
+	DNSCryptESVersionXChacha20Poly1305 = 2
+)
+
+// Error kinds reported by the DNSCrypt certificate functions.
+var (
+	ErrInvalidCertificate     = errors.New("Invalid DNSCrypt certificate")
+	ErrUnsupportedCertificate = errors.New("Unsupported DNSCrypt certificate construction")
+	ErrCertificateSignature   = errors.New("Invalid DNSCrypt certificate signature")
+	ErrCertificateValidity    = errors.New("DNSCrypt certificate is not valid at this time")
+)
+
+var dnsCryptCertMagic = []byte{'D', 'N', 'S', 'C'}
+
+// certificate magic(4) es-version(2) minor-version(2) signature(64)
+// resolver-pk(32) client-magic(8) serial(4) ts-start(4) ts-end(4) extensions
+const (
+	dnsCryptCertSignedOffset = 4 + 2 + 2 + ed25519.SignatureSize
+	dnsCryptCertMinSize      = dnsCryptCertSignedOffset + 32 + 8 + 4 + 4 + 4
+)
+
+// DNSCryptCertificate is a certificate of a DNSCrypt server, as published in
+// the TXT record of its provider name. It is signed by the provider key,
+// that is the public key of the server's stamp.
+type DNSCryptCertificate struct {
+	ESVersion    uint16
+	MinorVersion uint16
+	Signature    [ed25519.SignatureSize]byte
+	ResolverPk   [32]byte
+	ClientMagic  [8]byte
+	Serial       uint32
+	NotBefore    time.Time
+	NotAfter     time.Time
+	Extensions   []byte
+}
+
+// ParseDNSCryptCertificate decodes a DNSCrypt certificate, without checking
+// its signature. See VerifyDNSCryptCertificate.
+func ParseDNSCryptCertificate(bin []byte) (DNSCryptCertificate, error) {
+	var cert DNSCryptCertificate
+	if len(bin) < dnsCryptCertMinSize {
+		return cert, fmt.Errorf("%w (too short)", ErrInvalidCertificate)
+	}
+	if !bytes.Equal(bin[0:4], dnsCryptCertMagic) {
+		return cert, fmt.Errorf("%w (magic)", ErrInvalidCertificate)
+	}
+	cert.ESVersion = binary.BigEndian.Uint16(bin[4:6])
+	cert.MinorVersion = binary.BigEndian.Uint16(bin[6:8])
+	copy(cert.Signature[:], bin[8:dnsCryptCertSignedOffset])
+	pos := dnsCryptCertSignedOffset
+	copy(cert.ResolverPk[:], bin[pos:pos+32])
+	pos += 32
+	copy(cert.ClientMagic[:], bin[pos:pos+8])
+	pos += 8
+	cert.Serial = binary.BigEndian.Uint32(bin[pos : pos+4])
+	pos += 4
+	cert.NotBefore = time.Unix(int64(binary.BigEndian.Uint32(bin[pos:pos+4])), 0)
+	pos += 4
+	cert.NotAfter = time.Unix(int64(binary.BigEndian.Uint32(bin[pos:pos+4])), 0)
+	pos += 4
+	if pos < len(bin) {
+		cert.Extensions = append([]byte{}, bin[pos:]...)
+	}
+	return cert, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning the
+// certificate as published in a TXT record.
+func (cert *DNSCryptCertificate) MarshalBinary() ([]byte, error) {
+	bin := make([]byte, 0, dnsCryptCertMinSize+len(cert.Extensions))
+	bin = append(bin, dnsCryptCertMagic...)
+	bin = binary.BigEndian.AppendUint16(bin, cert.ESVersion)
+	bin = binary.BigEndian.AppendUint16(bin, cert.MinorVersion)
+	bin = append(bin, cert.Signature[:]...)
+	signedData, err := cert.signedData()
+	if err != nil {
+		return nil, err
+	}
+	return append(bin, signedData...), nil
+}
+
+// signedData returns the part of the certificate covered by the signature:
+// everything after the signature.
+func (cert *DNSCryptCertificate) signedData() ([]byte, error) {
+	notBefore, err := certificateTime(cert.NotBefore)
+	if err != nil {
+		return nil, err
+	}
+	notAfter, err := certificateTime(cert.NotAfter)
+	if err != nil {
+		return nil, err
+	}
+	bin := make([]byte, 0, dnsCryptCertMinSize-dnsCryptCertSignedOffset+len(cert.Extensions))
+	bin = append(bin, cert.ResolverPk[:]...)
+	bin = append(bin, cert.ClientMagic[:]...)
+	bin = binary.BigEndian.AppendUint32(bin, cert.Serial)
+	bin = binary.BigEndian.AppendUint32(bin, notBefore)
+	bin = binary.BigEndian.AppendUint32(bin, notAfter)
+	return append(bin, cert.Extensions...), nil
+}
+
+// certificateTime returns a time as the 32-bit Unix timestamp stored in
+// certificates, which cannot represent times before 1970 or after 2106.
+func certificateTime(t time.Time) (uint32, error) {
+	unix := t.Unix()
+	if unix < 0 || unix > math.MaxUint32 {
+		return 0, fmt.Errorf("%w (time out of range: %v)", ErrInvalidCertificate, t.UTC())
+	}
+	return uint32(unix), nil
+}
+
+// VerifyDNSCryptCertificate decodes a DNSCrypt certificate, and checks that
+// it was signed with the public key of the stamp, that its construction is
+// supported, and that it is valid at the given time.
+func VerifyDNSCryptCertificate(stamp ServerStamp, bin []byte, now time.Time) (DNSCryptCertificate, error) {
+	if stamp.Proto != StampProtoTypeDNSCrypt {
+		return DNSCryptCertificate{}, &FieldError{Proto: stamp.Proto, Field: FieldProto, Err: ErrUnsupportedProtocol}
+	}
+	if len(stamp.ServerPk) != ed25519.PublicKeySize {
+		return DNSCryptCertificate{}, &FieldError{Proto: stamp.Proto, Field: FieldPublicKey, Err: ErrPublicKeyLength}
+	}
+	cert, err := ParseDNSCryptCertificate(bin)
+	if err != nil {
+		return cert, err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(stamp.ServerPk), bin[dnsCryptCertSignedOffset:], cert.Signature[:]) {
+		return cert, ErrCertificateSignature
+	}
+	if cert.ESVersion != DNSCryptESVersionXSalsa20Poly1305 && cert.ESVersion != DNSCryptESVersionXChacha20Poly1305 {
+		return cert, fmt.Errorf("%w: es-version %d", ErrUnsupportedCertificate, cert.ESVersion)
+	}
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return cert, fmt.Errorf("%w: valid from %v to %v", ErrCertificateValidity, cert.NotBefore.UTC(), cert.NotAfter.UTC())
+	}
+	return cert, nil
+}
+
+// SelectDNSCryptCertificate verifies every certificate with
+// VerifyDNSCryptCertificate, and returns the best valid one, like
+// dnscrypt-proxy: the highest serial number wins, and XChacha20 certificates
+// are preferred to XSalsa20 certificates with the same serial number. If no
+// certificate is valid, the errors are combined with errors.Join.
+func SelectDNSCryptCertificate(stamp ServerStamp, certs [][]byte, now time.Time) (DNSCryptCertificate, error) {
+	var best *DNSCryptCertificate
+	var errs []error
+	for i, bin := range certs {
+		cert, err := VerifyDNSCryptCertificate(stamp, bin, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("certificate %d: %w", i, err))
+			continue
+		}
+		if best == nil || cert.Serial > best.Serial ||
+			(cert.Serial == best.Serial && cert.ESVersion > best.ESVersion) {
+			best = &cert
+		}
+	}
+	if best == nil {
+		if len(errs) == 0 {
+			return DNSCryptCertificate{}, fmt.Errorf("%w (no certificates)", ErrInvalidCertificate)
+		}
+		return DNSCryptCertificate{}, errors.Join(errs...)
+	}
+	return *best, nil
+}
@@ -0,0 +1,98 @@
+package dnsstamps
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testDNSCryptCert(providerSk ed25519.PrivateKey, esVersion uint16, serial uint32, notBefore, notAfter time.Time) []byte {
+	bin := append([]byte{}, dnsCryptCertMagic...)
+	bin = binary.BigEndian.AppendUint16(bin, esVersion)
+	bin = binary.BigEndian.AppendUint16(bin, 0)
+	bin = append(bin, make([]byte, ed25519.SignatureSize)...)
+	bin = append(bin, pk1...)
+	bin = append(bin, "magic123"...)
+	bin = binary.BigEndian.AppendUint32(bin, serial)
+	bin = binary.BigEndian.AppendUint32(bin, uint32(notBefore.Unix()))
+	bin = binary.BigEndian.AppendUint32(bin, uint32(notAfter.Unix()))
+	copy(bin[8:], ed25519.Sign(providerSk, bin[dnsCryptCertSignedOffset:]))
+	return bin
+}
+
+func TestVerifyDNSCryptCertificate(t *testing.T) {
+	providerPk, providerSk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp := ServerStamp{Proto: StampProtoTypeDNSCrypt, ServerAddrStr: "127.0.0.1:443", ServerPk: providerPk, ProviderName: "2.dnscrypt-cert.example.com"}
+	now := time.Unix(1700000000, 0)
+	bin := testDNSCryptCert(providerSk, DNSCryptESVersionXChacha20Poly1305, 42, now.Add(-time.Hour), now.Add(time.Hour))
+
+	cert, err := VerifyDNSCryptCertificate(stamp, bin, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.ESVersion != DNSCryptESVersionXChacha20Poly1305 || cert.Serial != 42 || string(cert.ClientMagic[:]) != "magic123" ||
+		string(cert.ResolverPk[:]) != string(pk1) || !cert.NotAfter.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected certificate %+v", cert)
+	}
+
+	if _, err := VerifyDNSCryptCertificate(stamp, bin, now.Add(2*time.Hour)); !errors.Is(err, ErrCertificateValidity) {
+		t.Errorf("expected %v, got %v", ErrCertificateValidity, err)
+	}
+	tampered := append([]byte{}, bin...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := VerifyDNSCryptCertificate(stamp, tampered, now); !errors.Is(err, ErrCertificateSignature) {
+		t.Errorf("expected %v, got %v", ErrCertificateSignature, err)
+	}
+	if _, err := VerifyDNSCryptCertificate(stamp, bin[:100], now); !errors.Is(err, ErrInvalidCertificate) {
+		t.Errorf("expected %v, got %v", ErrInvalidCertificate, err)
+	}
+	unsupported := testDNSCryptCert(providerSk, 3, 1, now.Add(-time.Hour), now.Add(time.Hour))
+	if _, err := VerifyDNSCryptCertificate(stamp, unsupported, now); !errors.Is(err, ErrUnsupportedCertificate) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedCertificate, err)
+	}
+}
+
+func TestSelectDNSCryptCertificate(t *testing.T) {
+	providerPk, providerSk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp := ServerStamp{Proto: StampProtoTypeDNSCrypt, ServerAddrStr: "127.0.0.1:443", ServerPk: providerPk, ProviderName: "2.dnscrypt-cert.example.com"}
+	now := time.Unix(1700000000, 0)
+	certs := [][]byte{
+		testDNSCryptCert(providerSk, DNSCryptESVersionXSalsa20Poly1305, 10, now.Add(-time.Hour), now.Add(time.Hour)),
+		testDNSCryptCert(providerSk, DNSCryptESVersionXChacha20Poly1305, 2, now.Add(-time.Hour), now.Add(time.Hour)),
+		testDNSCryptCert(providerSk, DNSCryptESVersionXChacha20Poly1305, 3, now.Add(-time.Hour), now.Add(time.Hour)),
+		testDNSCryptCert(providerSk, DNSCryptESVersionXChacha20Poly1305, 4, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+	}
+	cert, err := SelectDNSCryptCertificate(stamp, certs, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.ESVersion != DNSCryptESVersionXSalsa20Poly1305 || cert.Serial != 10 {
+		t.Errorf("expected the XSalsa20 certificate with a newer serial, got es-version %d, serial %d", cert.ESVersion, cert.Serial)
+	}
+
+	tied := [][]byte{
+		testDNSCryptCert(providerSk, DNSCryptESVersionXSalsa20Poly1305, 3, now.Add(-time.Hour), now.Add(time.Hour)),
+		certs[2],
+		certs[1],
+	}
+	cert, err = SelectDNSCryptCertificate(stamp, tied, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.ESVersion != DNSCryptESVersionXChacha20Poly1305 || cert.Serial != 3 {
+		t.Errorf("expected the XChacha20 certificate to break the tie, got es-version %d, serial %d", cert.ESVersion, cert.Serial)
+	}
+
+	if _, err := SelectDNSCryptCertificate(stamp, certs[3:], now); !errors.Is(err, ErrCertificateValidity) {
+		t.Errorf("expected %v, got %v", ErrCertificateValidity, err)
+	}
+}
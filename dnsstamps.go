@@ -40,26 +40,10 @@ const (
 )
 
 func (stampProtoType *StampProtoType) String() string {
-	switch *stampProtoType {
-	case StampProtoTypePlain:
-		return "Plain"
-	case StampProtoTypeDNSCrypt:
-		return "DNSCrypt"
-	case StampProtoTypeDoH:
-		return "DoH"
-	case StampProtoTypeTLS:
-		return "TLS"
-	case StampProtoTypeDoQ:
-		return "QUIC"
-	case StampProtoTypeODoHTarget:
-		return "oDoH target"
-	case StampProtoTypeDNSCryptRelay:
-		return "DNSCrypt relay"
-	case StampProtoTypeODoHRelay:
-		return "oDoH relay"
-	default:
-		return "(unknown)"
+	if codec, ok := LookupCodec(*stampProtoType); ok {
+		return codec.Name
 	}
+	return "(unknown)"
 }
 
 type ServerStamp struct {
@@ -71,6 +55,34 @@ type ServerStamp struct {
 	Props         ServerInformalProperties
 	Proto         StampProtoType
 	BootstrapIPs  []string
+	// Payload is the encoded stamp, after the protocol identifier, if the
+	// protocol is unknown. See ParseOptions.PreserveUnknown.
+	Payload []byte
+
+	encoding *rawEncoding
+}
+
+// Clone returns a deep copy of the stamp, that shares no memory with it.
+func (stamp *ServerStamp) Clone() ServerStamp {
+	clone := *stamp
+	if stamp.ServerPk != nil {
+		clone.ServerPk = append([]uint8{}, stamp.ServerPk...)
+	}
+	if stamp.Hashes != nil {
+		clone.Hashes = make([][]uint8, len(stamp.Hashes))
+		for i, hash := range stamp.Hashes {
+			if hash != nil {
+				clone.Hashes[i] = append([]uint8{}, hash...)
+			}
+		}
+	}
+	if stamp.BootstrapIPs != nil {
+		clone.BootstrapIPs = append([]string{}, stamp.BootstrapIPs...)
+	}
+	if stamp.Payload != nil {
+		clone.Payload = append([]byte{}, stamp.Payload...)
+	}
+	return clone
 }
 
 func NewDNSCryptServerStampFromLegacy(serverAddrStr string, serverPkStr string, providerName string, props ServerInformalProperties) (ServerStamp, error) {
@@ -91,37 +103,39 @@ func NewDNSCryptServerStampFromLegacy(serverAddrStr string, serverPkStr string,
 }
 
 func NewServerStampFromString(stampStr string) (ServerStamp, error) {
+	return ParseWithOptions(stampStr, ParseOptions{})
+}
+
+func decodeStampString(stampStr string) ([]byte, error) {
 	if !strings.HasPrefix(stampStr, "sdns:") {
-		return ServerStamp{}, errors.New("Stamps are expected to start with \"sdns:\"")
+		return nil, &ParseError{Kind: ErrInvalidScheme, Offset: -1}
 	}
 	stampStr = stampStr[5:]
 	stampStr = strings.TrimPrefix(stampStr, "//")
 	bin, err := base64.RawURLEncoding.Strict().DecodeString(stampStr)
 	if err != nil {
-		return ServerStamp{}, err
+		return nil, &ParseError{Kind: ErrInvalidEncoding, Offset: -1, Err: err}
 	}
+	return bin, nil
+}
+
+func newServerStampFromBinary(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	if len(bin) < 1 {
-		return ServerStamp{}, errors.New("Stamp is too short")
-	}
-
-	if bin[0] == uint8(StampProtoTypePlain) {
-		return newPlainDNSServerStamp(bin)
-	} else if bin[0] == uint8(StampProtoTypeDNSCrypt) {
-		return newDNSCryptServerStamp(bin)
-	} else if bin[0] == uint8(StampProtoTypeDoH) {
-		return newDoHServerStamp(bin)
-	} else if bin[0] == uint8(StampProtoTypeTLS) {
-		return newDoTServerStamp(bin)
-	} else if bin[0] == uint8(StampProtoTypeDoQ) {
-		return newDoQServerStamp(bin)
-	} else if bin[0] == uint8(StampProtoTypeODoHTarget) {
-		return newODoHTargetStamp(bin)
-	} else if bin[0] == uint8(StampProtoTypeDNSCryptRelay) {
-		return newDNSCryptRelayStamp(bin)
-	} else if bin[0] == uint8(StampProtoTypeODoHRelay) {
-		return newODoHRelayStamp(bin)
-	}
-	return ServerStamp{}, errors.New("Unsupported stamp version or protocol")
+		return ServerStamp{}, &ParseError{Kind: ErrTooShort, Offset: 0}
+	}
+
+	proto := StampProtoType(bin[0])
+	codec, ok := LookupCodec(proto)
+	if !ok {
+		if opts.PreserveUnknown {
+			return ServerStamp{Proto: proto, Payload: opts.slice(bin, 1, len(bin))}, nil
+		}
+		return ServerStamp{}, newParseError(ErrUnsupportedProtocol, proto, FieldProto, 0)
+	}
+	if codec.decode != nil {
+		return codec.decode(bin, opts)
+	}
+	return codec.Decode(bin)
 }
 
 func NewRelayAndServerStampFromString(stampStr string) (ServerStamp, ServerStamp, error) {
@@ -131,7 +145,7 @@ func NewRelayAndServerStampFromString(stampStr string) (ServerStamp, ServerStamp
 	stampStr = stampStr[7:]
 	parts := strings.Split(stampStr, "/")
 	if len(parts) != 2 {
-		return ServerStamp{}, ServerStamp{}, errors.New("This is not a relay+server stamp")
+		return ServerStamp{}, ServerStamp{}, ErrNotRelayedStamp
 	}
 	relayStamp, err := NewServerStampFromString(StampScheme + parts[0])
 	if err != nil {
@@ -141,20 +155,17 @@ func NewRelayAndServerStampFromString(stampStr string) (ServerStamp, ServerStamp
 	if err != nil {
 		return ServerStamp{}, ServerStamp{}, err
 	}
-	if relayStamp.Proto != StampProtoTypeDNSCryptRelay && relayStamp.Proto != StampProtoTypeODoHRelay {
-		return ServerStamp{}, ServerStamp{}, errors.New("First stamp is not a relay")
-	}
-	if !(serverStamp.Proto != StampProtoTypeDNSCryptRelay && serverStamp.Proto != StampProtoTypeODoHRelay) {
-		return ServerStamp{}, ServerStamp{}, errors.New("Second stamp is a relay")
+	if err := checkRelayPair(&relayStamp, &serverStamp); err != nil {
+		return ServerStamp{}, ServerStamp{}, err
 	}
 	return relayStamp, serverStamp, nil
 }
 
 // id(u8)=0x00 props 0x00 addrLen(1) serverAddr
-func newPlainDNSServerStamp(bin []byte) (ServerStamp, error) {
+func newPlainDNSServerStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypePlain}
 	if len(bin) < 1+8+1+1 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	stamp.Props = ServerInformalProperties(binary.LittleEndian.Uint64(bin[1:9]))
 	binLen := len(bin)
@@ -162,43 +173,27 @@ func newPlainDNSServerStamp(bin []byte) (ServerStamp, error) {
 
 	length := int(bin[pos])
 	if 1+length > binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldAddress, pos)
 	}
 	pos++
 	stamp.ServerAddrStr = string(bin[pos : pos+length])
+	if err := parseServerAddr(&stamp, pos, DefaultDNSPort, false); err != nil {
+		return stamp, err
+	}
 	pos += length
 
-	colIndex := strings.LastIndex(stamp.ServerAddrStr, ":")
-	bracketIndex := strings.LastIndex(stamp.ServerAddrStr, "]")
-	if colIndex < bracketIndex {
-		colIndex = -1
-	}
-	if colIndex < 0 {
-		colIndex = len(stamp.ServerAddrStr) // DefaultDNSPort
-		stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, DefaultDNSPort)
-	}
-	if colIndex >= len(stamp.ServerAddrStr)-1 {
-		return stamp, errors.New("Invalid stamp (empty port)")
-	}
-	ipOnly := stamp.ServerAddrStr[:colIndex]
-	if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
-		return stamp, errors.New("Invalid stamp (port range)")
-	}
-	if net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
-		return stamp, errors.New("Invalid stamp (IP address)")
-	}
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 	return stamp, nil
 }
 
 // id(u8)=0x01 props addrLen(1) serverAddr pkStrlen(1) pkStr providerNameLen(1) providerName
 
-func newDNSCryptServerStamp(bin []byte) (ServerStamp, error) {
+func newDNSCryptServerStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypeDNSCrypt}
 	if len(bin) < 66 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	stamp.Props = ServerInformalProperties(binary.LittleEndian.Uint64(bin[1:9]))
 	binLen := len(bin)
@@ -206,60 +201,43 @@ func newDNSCryptServerStamp(bin []byte) (ServerStamp, error) {
 
 	length := int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldAddress, pos)
 	}
 	pos++
 	stamp.ServerAddrStr = string(bin[pos : pos+length])
-	pos += length
-
-	colIndex := strings.LastIndex(stamp.ServerAddrStr, ":")
-	bracketIndex := strings.LastIndex(stamp.ServerAddrStr, "]")
-	if colIndex < bracketIndex {
-		colIndex = -1
-	}
-	if colIndex < 0 {
-		colIndex = len(stamp.ServerAddrStr)
-		stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, DefaultPort)
-	}
-	if colIndex >= len(stamp.ServerAddrStr)-1 {
-		return stamp, errors.New("Invalid stamp (empty port)")
-	}
-	ipOnly := stamp.ServerAddrStr[:colIndex]
-	if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
-		return stamp, errors.New("Invalid stamp (port range)")
-	}
-	if net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
-		return stamp, errors.New("Invalid stamp (IP address)")
+	if err := parseServerAddr(&stamp, pos, DefaultPort, false); err != nil {
+		return stamp, err
 	}
+	pos += length
 
 	length = int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldPublicKey, pos)
 	}
 	pos++
-	stamp.ServerPk = bin[pos : pos+length]
+	stamp.ServerPk = opts.slice(bin, pos, pos+length)
 	pos += length
 
 	length = int(bin[pos])
 	if length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldProviderName, pos)
 	}
 	pos++
 	stamp.ProviderName = string(bin[pos : pos+length])
 	pos += length
 
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 	return stamp, nil
 }
 
 // id(u8)=0x02 props addrLen(1) serverAddr hashLen(1) hash hostNameLen(1) hostName pathLen(1) path
 
-func newDoHServerStamp(bin []byte) (ServerStamp, error) {
+func newDoHServerStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypeDoH}
 	if len(bin) < 15 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	stamp.Props = ServerInformalProperties(binary.LittleEndian.Uint64(bin[1:9]))
 	binLen := len(bin)
@@ -267,24 +245,25 @@ func newDoHServerStamp(bin []byte) (ServerStamp, error) {
 
 	length := int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldAddress, pos)
 	}
 	pos++
 	stamp.ServerAddrStr = string(bin[pos : pos+length])
+	addrPos := pos
 	pos += length
 
 	for {
 		vlen := int(bin[pos])
 		length = vlen & ^0x80
 		if 1+length >= binLen-pos {
-			return stamp, errors.New("Invalid stamp")
+			return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldHash, pos)
+		}
+		if length > 0 && length != 32 {
+			return stamp, newParseError(ErrHashLength, stamp.Proto, FieldHash, pos)
 		}
 		pos++
 		if length > 0 {
-			if length != 32 {
-				return stamp, errors.New("Invalid stamp (certificate hash must be 32 bytes)")
-			}
-			stamp.Hashes = append(stamp.Hashes, bin[pos:pos+length])
+			stamp.Hashes = append(stamp.Hashes, opts.slice(bin, pos, pos+length))
 		}
 		pos += length
 		if vlen&0x80 != 0x80 {
@@ -294,7 +273,7 @@ func newDoHServerStamp(bin []byte) (ServerStamp, error) {
 
 	length = int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldProviderName, pos)
 	}
 	pos++
 	stamp.ProviderName = string(bin[pos : pos+length])
@@ -302,58 +281,24 @@ func newDoHServerStamp(bin []byte) (ServerStamp, error) {
 
 	length = int(bin[pos])
 	if length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldPath, pos)
 	}
 	pos++
 	stamp.Path = string(bin[pos : pos+length])
 	pos += length
 
-	// Parse optional bootstrap IP addresses (VLP format)
-	if pos < binLen {
-		for {
-			if pos >= binLen {
-				break
-			}
-			vlen := int(bin[pos])
-			length = vlen & ^0x80
-			if 1+length > binLen-pos {
-				return stamp, errors.New("Invalid stamp")
-			}
-			pos++
-			if length > 0 {
-				bootstrapIP := string(bin[pos : pos+length])
-				stamp.BootstrapIPs = append(stamp.BootstrapIPs, bootstrapIP)
-			}
-			pos += length
-			if vlen&0x80 != 0x80 {
-				break
-			}
-		}
+	pos, err := parseBootstrapIPs(&stamp, bin, pos, opts)
+	if err != nil {
+		return stamp, err
 	}
 
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 
 	if len(stamp.ServerAddrStr) > 0 {
-		colIndex := strings.LastIndex(stamp.ServerAddrStr, ":")
-		bracketIndex := strings.LastIndex(stamp.ServerAddrStr, "]")
-		if colIndex < bracketIndex {
-			colIndex = -1
-		}
-		if colIndex < 0 {
-			colIndex = len(stamp.ServerAddrStr)
-			stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, DefaultPort)
-		}
-		if colIndex >= len(stamp.ServerAddrStr)-1 {
-			return stamp, errors.New("Invalid stamp (empty port)")
-		}
-		ipOnly := stamp.ServerAddrStr[:colIndex]
-		if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
-			return stamp, errors.New("Invalid stamp (port range)")
-		}
-		if net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
-			return stamp, errors.New("Invalid stamp (IP address)")
+		if err := parseServerAddr(&stamp, addrPos, DefaultPort, false); err != nil {
+			return stamp, err
 		}
 	}
 
@@ -362,10 +307,10 @@ func newDoHServerStamp(bin []byte) (ServerStamp, error) {
 
 // id(u8)=0x03 props addrLen(1) serverAddr hashLen(1) hash hostNameLen(1) hostName [ bootstrapLen(1) bootstrap ]
 
-func newDoTServerStamp(bin []byte) (ServerStamp, error) {
+func newDoTServerStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypeTLS}
 	if len(bin) < 13 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	stamp.Props = ServerInformalProperties(binary.LittleEndian.Uint64(bin[1:9]))
 	binLen := len(bin)
@@ -373,24 +318,25 @@ func newDoTServerStamp(bin []byte) (ServerStamp, error) {
 
 	length := int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldAddress, pos)
 	}
 	pos++
 	stamp.ServerAddrStr = string(bin[pos : pos+length])
+	addrPos := pos
 	pos += length
 
 	for {
 		vlen := int(bin[pos])
 		length = vlen & ^0x80
 		if 1+length >= binLen-pos {
-			return stamp, errors.New("Invalid stamp")
+			return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldHash, pos)
+		}
+		if length > 0 && length != 32 {
+			return stamp, newParseError(ErrHashLength, stamp.Proto, FieldHash, pos)
 		}
 		pos++
 		if length > 0 {
-			if length != 32 {
-				return stamp, errors.New("Invalid stamp (certificate hash must be 32 bytes)")
-			}
-			stamp.Hashes = append(stamp.Hashes, bin[pos:pos+length])
+			stamp.Hashes = append(stamp.Hashes, opts.slice(bin, pos, pos+length))
 		}
 		pos += length
 		if vlen&0x80 != 0x80 {
@@ -400,58 +346,24 @@ func newDoTServerStamp(bin []byte) (ServerStamp, error) {
 
 	length = int(bin[pos])
 	if length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldProviderName, pos)
 	}
 	pos++
 	stamp.ProviderName = string(bin[pos : pos+length])
 	pos += length
 
-	// Parse optional bootstrap IP addresses (VLP format)
-	if pos < binLen {
-		for {
-			if pos >= binLen {
-				break
-			}
-			vlen := int(bin[pos])
-			length = vlen & ^0x80
-			if 1+length > binLen-pos {
-				return stamp, errors.New("Invalid stamp")
-			}
-			pos++
-			if length > 0 {
-				bootstrapIP := string(bin[pos : pos+length])
-				stamp.BootstrapIPs = append(stamp.BootstrapIPs, bootstrapIP)
-			}
-			pos += length
-			if vlen&0x80 != 0x80 {
-				break
-			}
-		}
+	pos, err := parseBootstrapIPs(&stamp, bin, pos, opts)
+	if err != nil {
+		return stamp, err
 	}
 
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 
 	if len(stamp.ServerAddrStr) > 0 {
-		colIndex := strings.LastIndex(stamp.ServerAddrStr, ":")
-		bracketIndex := strings.LastIndex(stamp.ServerAddrStr, "]")
-		if colIndex < bracketIndex {
-			colIndex = -1
-		}
-		if colIndex < 0 {
-			colIndex = len(stamp.ServerAddrStr)
-			stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, DefaultDoTPort)
-		}
-		if colIndex >= len(stamp.ServerAddrStr)-1 {
-			return stamp, errors.New("Invalid stamp (empty port)")
-		}
-		ipOnly := stamp.ServerAddrStr[:colIndex]
-		if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
-			return stamp, errors.New("Invalid stamp (port range)")
-		}
-		if ipOnly != "" && net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
-			return stamp, errors.New("Invalid stamp (IP address)")
+		if err := parseServerAddr(&stamp, addrPos, DefaultDoTPort, true); err != nil {
+			return stamp, err
 		}
 	}
 
@@ -460,10 +372,10 @@ func newDoTServerStamp(bin []byte) (ServerStamp, error) {
 
 // id(u8)=0x04 props addrLen(1) serverAddr hashLen(1) hash hostNameLen(1) hostName [ bootstrapLen(1) bootstrap ]
 
-func newDoQServerStamp(bin []byte) (ServerStamp, error) {
+func newDoQServerStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypeDoQ}
 	if len(bin) < 13 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	stamp.Props = ServerInformalProperties(binary.LittleEndian.Uint64(bin[1:9]))
 	binLen := len(bin)
@@ -471,24 +383,25 @@ func newDoQServerStamp(bin []byte) (ServerStamp, error) {
 
 	length := int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldAddress, pos)
 	}
 	pos++
 	stamp.ServerAddrStr = string(bin[pos : pos+length])
+	addrPos := pos
 	pos += length
 
 	for {
 		vlen := int(bin[pos])
 		length = vlen & ^0x80
 		if 1+length >= binLen-pos {
-			return stamp, errors.New("Invalid stamp")
+			return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldHash, pos)
+		}
+		if length > 0 && length != 32 {
+			return stamp, newParseError(ErrHashLength, stamp.Proto, FieldHash, pos)
 		}
 		pos++
 		if length > 0 {
-			if length != 32 {
-				return stamp, errors.New("Invalid stamp (certificate hash must be 32 bytes)")
-			}
-			stamp.Hashes = append(stamp.Hashes, bin[pos:pos+length])
+			stamp.Hashes = append(stamp.Hashes, opts.slice(bin, pos, pos+length))
 		}
 		pos += length
 		if vlen&0x80 != 0x80 {
@@ -498,58 +411,24 @@ func newDoQServerStamp(bin []byte) (ServerStamp, error) {
 
 	length = int(bin[pos])
 	if length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldProviderName, pos)
 	}
 	pos++
 	stamp.ProviderName = string(bin[pos : pos+length])
 	pos += length
 
-	// Parse optional bootstrap IP addresses (VLP format)
-	if pos < binLen {
-		for {
-			if pos >= binLen {
-				break
-			}
-			vlen := int(bin[pos])
-			length = vlen & ^0x80
-			if 1+length > binLen-pos {
-				return stamp, errors.New("Invalid stamp")
-			}
-			pos++
-			if length > 0 {
-				bootstrapIP := string(bin[pos : pos+length])
-				stamp.BootstrapIPs = append(stamp.BootstrapIPs, bootstrapIP)
-			}
-			pos += length
-			if vlen&0x80 != 0x80 {
-				break
-			}
-		}
+	pos, err := parseBootstrapIPs(&stamp, bin, pos, opts)
+	if err != nil {
+		return stamp, err
 	}
 
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 
 	if len(stamp.ServerAddrStr) > 0 {
-		colIndex := strings.LastIndex(stamp.ServerAddrStr, ":")
-		bracketIndex := strings.LastIndex(stamp.ServerAddrStr, "]")
-		if colIndex < bracketIndex {
-			colIndex = -1
-		}
-		if colIndex < 0 {
-			colIndex = len(stamp.ServerAddrStr)
-			stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, DefaultDoTPort)
-		}
-		if colIndex >= len(stamp.ServerAddrStr)-1 {
-			return stamp, errors.New("Invalid stamp (empty port)")
-		}
-		ipOnly := stamp.ServerAddrStr[:colIndex]
-		if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
-			return stamp, errors.New("Invalid stamp (port range)")
-		}
-		if ipOnly != "" && net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
-			return stamp, errors.New("Invalid stamp (IP address)")
+		if err := parseServerAddr(&stamp, addrPos, DefaultDoTPort, true); err != nil {
+			return stamp, err
 		}
 	}
 
@@ -558,10 +437,10 @@ func newDoQServerStamp(bin []byte) (ServerStamp, error) {
 
 // id(u8)=0x05 props hostNameLen(1) hostName pathLen(1) path
 
-func newODoHTargetStamp(bin []byte) (ServerStamp, error) {
+func newODoHTargetStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypeODoHTarget}
 	if len(bin) < 12 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	stamp.Props = ServerInformalProperties(binary.LittleEndian.Uint64(bin[1:9]))
 	binLen := len(bin)
@@ -569,7 +448,7 @@ func newODoHTargetStamp(bin []byte) (ServerStamp, error) {
 
 	length := int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldProviderName, pos)
 	}
 	pos++
 	stamp.ProviderName = string(bin[pos : pos+length])
@@ -577,14 +456,14 @@ func newODoHTargetStamp(bin []byte) (ServerStamp, error) {
 
 	length = int(bin[pos])
 	if length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldPath, pos)
 	}
 	pos++
 	stamp.Path = string(bin[pos : pos+length])
 	pos += length
 
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 
 	return stamp, nil
@@ -592,52 +471,36 @@ func newODoHTargetStamp(bin []byte) (ServerStamp, error) {
 
 // id(u8)=0x81 addrLen(1) serverAddr
 
-func newDNSCryptRelayStamp(bin []byte) (ServerStamp, error) {
+func newDNSCryptRelayStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypeDNSCryptRelay}
 	if len(bin) < 9 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	binLen := len(bin)
 	pos := 1
 	length := int(bin[pos])
 	if 1+length > binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldAddress, pos)
 	}
 	pos++
 	stamp.ServerAddrStr = string(bin[pos : pos+length])
+	if err := parseServerAddr(&stamp, pos, DefaultPort, false); err != nil {
+		return stamp, err
+	}
 	pos += length
 
-	colIndex := strings.LastIndex(stamp.ServerAddrStr, ":")
-	bracketIndex := strings.LastIndex(stamp.ServerAddrStr, "]")
-	if colIndex < bracketIndex {
-		colIndex = -1
-	}
-	if colIndex < 0 {
-		colIndex = len(stamp.ServerAddrStr)
-		stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, DefaultPort)
-	}
-	if colIndex >= len(stamp.ServerAddrStr)-1 {
-		return stamp, errors.New("Invalid stamp (empty port)")
-	}
-	ipOnly := stamp.ServerAddrStr[:colIndex]
-	if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
-		return stamp, errors.New("Invalid stamp (port range)")
-	}
-	if net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
-		return stamp, errors.New("Invalid stamp (IP address)")
-	}
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 	return stamp, nil
 }
 
 // id(u8)=0x85 props addrLen(1) serverAddr hashLen(1) hash hostNameLen(1) hostName pathLen(1) path
 
-func newODoHRelayStamp(bin []byte) (ServerStamp, error) {
+func newODoHRelayStamp(bin []byte, opts *ParseOptions) (ServerStamp, error) {
 	stamp := ServerStamp{Proto: StampProtoTypeODoHRelay}
 	if len(bin) < 13 {
-		return stamp, errors.New("Stamp is too short")
+		return stamp, newParseError(ErrTooShort, stamp.Proto, "", len(bin))
 	}
 	stamp.Props = ServerInformalProperties(binary.LittleEndian.Uint64(bin[1:9]))
 	binLen := len(bin)
@@ -645,24 +508,25 @@ func newODoHRelayStamp(bin []byte) (ServerStamp, error) {
 
 	length := int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldAddress, pos)
 	}
 	pos++
 	stamp.ServerAddrStr = string(bin[pos : pos+length])
+	addrPos := pos
 	pos += length
 
 	for {
 		vlen := int(bin[pos])
 		length = vlen & ^0x80
 		if 1+length >= binLen-pos {
-			return stamp, errors.New("Invalid stamp")
+			return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldHash, pos)
+		}
+		if length > 0 && length != 32 {
+			return stamp, newParseError(ErrHashLength, stamp.Proto, FieldHash, pos)
 		}
 		pos++
 		if length > 0 {
-			if length != 32 {
-				return stamp, errors.New("Invalid stamp (certificate hash must be 32 bytes)")
-			}
-			stamp.Hashes = append(stamp.Hashes, bin[pos:pos+length])
+			stamp.Hashes = append(stamp.Hashes, opts.slice(bin, pos, pos+length))
 		}
 		pos += length
 		if vlen&0x80 != 0x80 {
@@ -672,7 +536,7 @@ func newODoHRelayStamp(bin []byte) (ServerStamp, error) {
 
 	length = int(bin[pos])
 	if 1+length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldProviderName, pos)
 	}
 	pos++
 	stamp.ProviderName = string(bin[pos : pos+length])
@@ -680,62 +544,98 @@ func newODoHRelayStamp(bin []byte) (ServerStamp, error) {
 
 	length = int(bin[pos])
 	if length >= binLen-pos {
-		return stamp, errors.New("Invalid stamp")
+		return stamp, newParseError(ErrInvalidStamp, stamp.Proto, FieldPath, pos)
 	}
 	pos++
 	stamp.Path = string(bin[pos : pos+length])
 	pos += length
 
-	// Parse optional bootstrap IP addresses (VLP format)
-	if pos < binLen {
-		for {
-			if pos >= binLen {
-				break
-			}
-			vlen := int(bin[pos])
-			length = vlen & ^0x80
-			if 1+length > binLen-pos {
-				return stamp, errors.New("Invalid stamp")
-			}
-			pos++
-			if length > 0 {
-				bootstrapIP := string(bin[pos : pos+length])
-				stamp.BootstrapIPs = append(stamp.BootstrapIPs, bootstrapIP)
-			}
-			pos += length
-			if vlen&0x80 != 0x80 {
-				break
-			}
-		}
+	pos, err := parseBootstrapIPs(&stamp, bin, pos, opts)
+	if err != nil {
+		return stamp, err
 	}
 
 	if pos != binLen {
-		return stamp, errors.New("Invalid stamp (garbage after end)")
+		return stamp, newParseError(ErrGarbageAfterEnd, stamp.Proto, "", pos)
 	}
 
 	if len(stamp.ServerAddrStr) > 0 {
-		colIndex := strings.LastIndex(stamp.ServerAddrStr, ":")
-		bracketIndex := strings.LastIndex(stamp.ServerAddrStr, "]")
-		if colIndex < bracketIndex {
-			colIndex = -1
+		if err := parseServerAddr(&stamp, addrPos, DefaultPort, false); err != nil {
+			return stamp, err
 		}
-		if colIndex < 0 {
-			colIndex = len(stamp.ServerAddrStr)
-			stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, DefaultPort)
-		}
-		if colIndex >= len(stamp.ServerAddrStr)-1 {
-			return stamp, errors.New("Invalid stamp (empty port)")
-		}
-		ipOnly := stamp.ServerAddrStr[:colIndex]
-		if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
-			return stamp, errors.New("Invalid stamp (port range)")
+	}
+
+	return stamp, nil
+}
+
+// parseBootstrapIPs parses the optional bootstrap IP addresses (VLP format)
+// starting at pos, and returns the position after them. Unless
+// opts.LenientBootstrapIPs is set, addresses must be unique IP literals,
+// optionally with a port, and are normalized.
+func parseBootstrapIPs(stamp *ServerStamp, bin []byte, pos int, opts *ParseOptions) (int, error) {
+	binLen := len(bin)
+	for pos < binLen {
+		vlen := int(bin[pos])
+		length := vlen & ^0x80
+		if 1+length > binLen-pos {
+			return pos, newParseError(ErrInvalidStamp, stamp.Proto, FieldBootstrapIP, pos)
+		}
+		if !opts.LenientBootstrapIPs {
+			bootstrapIP, err := normalizeBootstrapIP(string(bin[pos+1 : pos+1+length]))
+			if err != nil {
+				return pos, newParseError(ErrInvalidBootstrapIP, stamp.Proto, FieldBootstrapIP, pos)
+			}
+			for _, existing := range stamp.BootstrapIPs {
+				if existing == bootstrapIP {
+					return pos, newParseError(ErrDuplicateBootstrapIP, stamp.Proto, FieldBootstrapIP, pos)
+				}
+			}
+			stamp.BootstrapIPs = append(stamp.BootstrapIPs, bootstrapIP)
+		} else if length > 0 {
+			stamp.BootstrapIPs = append(stamp.BootstrapIPs, string(bin[pos+1:pos+1+length]))
 		}
-		if net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
-			return stamp, errors.New("Invalid stamp (IP address)")
+		pos += 1 + length
+		if vlen&0x80 != 0x80 {
+			break
 		}
 	}
+	return pos, nil
+}
 
-	return stamp, nil
+// parseServerAddr checks the IP address and port of stamp.ServerAddrStr,
+// found at offset in the binary stamp, and appends defaultPort if no port
+// was given.
+func parseServerAddr(stamp *ServerStamp, offset int, defaultPort int, allowEmptyIP bool) error {
+	colIndex := portIndex(stamp.ServerAddrStr)
+	if colIndex < 0 {
+		colIndex = len(stamp.ServerAddrStr)
+		stamp.ServerAddrStr = fmt.Sprintf("%s:%d", stamp.ServerAddrStr, defaultPort)
+	}
+	if colIndex >= len(stamp.ServerAddrStr)-1 {
+		return newParseError(ErrEmptyPort, stamp.Proto, FieldAddress, offset)
+	}
+	ipOnly := stamp.ServerAddrStr[:colIndex]
+	if err := validatePort(stamp.ServerAddrStr[colIndex+1:]); err != nil {
+		return newParseError(ErrPortRange, stamp.Proto, FieldAddress, offset)
+	}
+	if allowEmptyIP && ipOnly == "" {
+		return nil
+	}
+	if net.ParseIP(strings.TrimRight(strings.TrimLeft(ipOnly, "["), "]")) == nil {
+		return newParseError(ErrInvalidIP, stamp.Proto, FieldAddress, offset)
+	}
+	return nil
+}
+
+// portIndex returns the index of the colon before the port in an address,
+// or -1 if the address has no port.
+func portIndex(addr string) int {
+	colIndex := strings.LastIndex(addr, ":")
+	bracketIndex := strings.LastIndex(addr, "]")
+	if colIndex < bracketIndex {
+		colIndex = -1
+	}
+	return colIndex
 }
 
 func validatePort(port string) error {
@@ -746,28 +646,33 @@ func validatePort(port string) error {
 	return nil
 }
 
+// String returns the sdns:// form of the stamp, or an empty string if the
+// protocol is unknown or if a field is too long to be encoded. Unlike
+// Encode(), it doesn't check the server address, and it silently omits the
+// fields that the protocol doesn't encode.
 func (stamp *ServerStamp) String() string {
-	if stamp.Proto == StampProtoTypePlain {
-		return stamp.plainStrng()
-	} else if stamp.Proto == StampProtoTypeDNSCrypt {
-		return stamp.dnsCryptString()
-	} else if stamp.Proto == StampProtoTypeDoH {
-		return stamp.dohString()
-	} else if stamp.Proto == StampProtoTypeTLS {
-		return stamp.dotString()
-	} else if stamp.Proto == StampProtoTypeDoQ {
-		return stamp.doqString()
-	} else if stamp.Proto == StampProtoTypeODoHTarget {
-		return stamp.oDohTargetString()
-	} else if stamp.Proto == StampProtoTypeDNSCryptRelay {
-		return stamp.dnsCryptRelayString()
-	} else if stamp.Proto == StampProtoTypeODoHRelay {
-		return stamp.oDohRelayString()
-	}
-	panic("Unsupported protocol")
+	bin, err := stamp.bytes()
+	if err != nil {
+		return ""
+	}
+	return StampScheme + base64.RawURLEncoding.EncodeToString(bin)
 }
 
-func (stamp *ServerStamp) plainStrng() string {
+func (stamp *ServerStamp) bytes() ([]byte, error) {
+	if stamp.encoding != nil && stamp.encoding.matches(stamp) {
+		return append([]byte{}, stamp.encoding.bin...), nil
+	}
+	codec, ok := LookupCodec(stamp.Proto)
+	if !ok {
+		if stamp.Payload != nil {
+			return append([]byte{uint8(stamp.Proto)}, stamp.Payload...), nil
+		}
+		return nil, &FieldError{Proto: stamp.Proto, Field: FieldProto, Err: ErrUnsupportedProtocol}
+	}
+	return codec.Encode(stamp)
+}
+
+func (stamp *ServerStamp) plainBytes() ([]byte, error) {
 	bin := make([]uint8, 9)
 	bin[0] = uint8(StampProtoTypePlain)
 	binary.LittleEndian.PutUint64(bin[1:9], uint64(stamp.Props))
@@ -778,12 +683,10 @@ func (stamp *ServerStamp) plainStrng() string {
 	}
 	bin = append(bin, uint8(len(serverAddrStr)))
 	bin = append(bin, []uint8(serverAddrStr)...)
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
 
-func (stamp *ServerStamp) dnsCryptString() string {
+func (stamp *ServerStamp) dnsCryptBytes() ([]byte, error) {
 	bin := make([]uint8, 9)
 	bin[0] = uint8(StampProtoTypeDNSCrypt)
 	binary.LittleEndian.PutUint64(bin[1:9], uint64(stamp.Props))
@@ -801,12 +704,10 @@ func (stamp *ServerStamp) dnsCryptString() string {
 	bin = append(bin, uint8(len(stamp.ProviderName)))
 	bin = append(bin, []uint8(stamp.ProviderName)...)
 
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
 
-func (stamp *ServerStamp) dohString() string {
+func (stamp *ServerStamp) dohBytes() ([]byte, error) {
 	bin := make([]uint8, 9)
 	bin[0] = uint8(StampProtoTypeDoH)
 	binary.LittleEndian.PutUint64(bin[1:9], uint64(stamp.Props))
@@ -851,12 +752,10 @@ func (stamp *ServerStamp) dohString() string {
 		}
 	}
 
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
 
-func (stamp *ServerStamp) dotString() string {
+func (stamp *ServerStamp) dotBytes() ([]byte, error) {
 	bin := make([]uint8, 9)
 	bin[0] = uint8(StampProtoTypeTLS)
 	binary.LittleEndian.PutUint64(bin[1:9], uint64(stamp.Props))
@@ -898,12 +797,10 @@ func (stamp *ServerStamp) dotString() string {
 		}
 	}
 
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
 
-func (stamp *ServerStamp) doqString() string {
+func (stamp *ServerStamp) doqBytes() ([]byte, error) {
 	bin := make([]uint8, 9)
 	bin[0] = uint8(StampProtoTypeDoQ)
 	binary.LittleEndian.PutUint64(bin[1:9], uint64(stamp.Props))
@@ -945,12 +842,10 @@ func (stamp *ServerStamp) doqString() string {
 		}
 	}
 
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
 
-func (stamp *ServerStamp) oDohTargetString() string {
+func (stamp *ServerStamp) oDohTargetBytes() ([]byte, error) {
 	bin := make([]uint8, 9)
 	bin[0] = uint8(StampProtoTypeODoHTarget)
 	binary.LittleEndian.PutUint64(bin[1:9], uint64(stamp.Props))
@@ -961,12 +856,10 @@ func (stamp *ServerStamp) oDohTargetString() string {
 	bin = append(bin, uint8(len(stamp.Path)))
 	bin = append(bin, []uint8(stamp.Path)...)
 
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
 
-func (stamp *ServerStamp) dnsCryptRelayString() string {
+func (stamp *ServerStamp) dnsCryptRelayBytes() ([]byte, error) {
 	bin := make([]uint8, 1)
 	bin[0] = uint8(StampProtoTypeDNSCryptRelay)
 
@@ -977,12 +870,10 @@ func (stamp *ServerStamp) dnsCryptRelayString() string {
 	bin = append(bin, uint8(len(serverAddrStr)))
 	bin = append(bin, []uint8(serverAddrStr)...)
 
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
 
-func (stamp *ServerStamp) oDohRelayString() string {
+func (stamp *ServerStamp) oDohRelayBytes() ([]byte, error) {
 	bin := make([]uint8, 9)
 	bin[0] = uint8(StampProtoTypeODoHRelay)
 	binary.LittleEndian.PutUint64(bin[1:9], uint64(stamp.Props))
@@ -1027,7 +918,5 @@ func (stamp *ServerStamp) oDohRelayString() string {
 		}
 	}
 
-	str := base64.RawURLEncoding.EncodeToString(bin)
-
-	return StampScheme + str
+	return bin, nil
 }
@@ -1,7 +1,11 @@
 package dnsstamps
 
 import (
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -1058,3 +1062,153 @@ func TestDoT_InvalidHashLength_MultipleHashes_OneInvalid(t *testing.T) {
 		t.Errorf("expected certificate hash error, got: %v", err)
 	}
 }
+
+// Bootstrap IP address validation tests
+
+func bootstrapTestStamp(bootstrapIPs ...string) string {
+	bin := []byte{uint8(StampProtoTypeTLS), 0, 0, 0, 0, 0, 0, 0, 0, 7}
+	bin = append(bin, "1.1.1.1"...)
+	bin = append(bin, 0, 18)
+	bin = append(bin, "cloudflare-dns.com"...)
+	for i, bootstrapIP := range bootstrapIPs {
+		vlen := len(bootstrapIP)
+		if i < len(bootstrapIPs)-1 {
+			vlen |= 0x80
+		}
+		bin = append(bin, uint8(vlen))
+		bin = append(bin, bootstrapIP...)
+	}
+	return StampScheme + base64.RawURLEncoding.EncodeToString(bin)
+}
+
+func TestBootstrapIPs_Normalized(t *testing.T) {
+	parsedStamp, err := NewServerStampFromString(bootstrapTestStamp("1.0.0.1", "2606:4700:4700:0:0:0:0:1001", "[2606:4700:4700::1111]:53"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedIPs := []string{"1.0.0.1", "2606:4700:4700::1001", "[2606:4700:4700::1111]:53"}
+	if len(parsedStamp.BootstrapIPs) != len(expectedIPs) {
+		t.Fatalf("expected %d bootstrap IPs but got %d", len(expectedIPs), len(parsedStamp.BootstrapIPs))
+	}
+	for i, expectedIP := range expectedIPs {
+		if parsedStamp.BootstrapIPs[i] != expectedIP {
+			t.Errorf("expected bootstrap IP %q at index %d but got %q", expectedIP, i, parsedStamp.BootstrapIPs[i])
+		}
+	}
+}
+
+func TestBootstrapIPs_Invalid(t *testing.T) {
+	tests := []struct {
+		bootstrapIPs []string
+		err          error
+	}{
+		{[]string{"dns.example.com"}, ErrInvalidBootstrapIP},
+		{[]string{"1.1.1.1", ""}, ErrInvalidBootstrapIP},
+		{[]string{"1.1.1.1:99999"}, ErrInvalidBootstrapIP},
+		{[]string{"2606:4700:4700::1111", "2606:4700:4700:0::1111"}, ErrDuplicateBootstrapIP},
+	}
+	for _, test := range tests {
+		stampStr := bootstrapTestStamp(test.bootstrapIPs...)
+		_, err := NewServerStampFromString(stampStr)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.bootstrapIPs, test.err, err)
+		}
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) && parseErr.Field != FieldBootstrapIP {
+			t.Errorf("%v: expected an error on the bootstrap IPs, got %+v", test.bootstrapIPs, parseErr)
+		}
+
+		parsedStamp, err := ParseWithOptions(stampStr, ParseOptions{LenientBootstrapIPs: true})
+		if err != nil {
+			t.Errorf("%v: unexpected error in lenient mode: %v", test.bootstrapIPs, err)
+			continue
+		}
+		if ps := parsedStamp.String(); len(parsedStamp.BootstrapIPs) == len(test.bootstrapIPs) && ps != stampStr {
+			t.Errorf("re-parsed stamp string is %q, but %q expected", ps, stampStr)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	stamp := ServerStamp{
+		Proto:         StampProtoTypeDoH,
+		ServerAddrStr: "1.1.1.1",
+		ProviderName:  "cloudflare-dns.com",
+		Path:          "/dns-query",
+		Hashes:        [][]uint8{append([]uint8{}, pk1...)},
+		BootstrapIPs:  []string{"1.1.1.1"},
+	}
+	clone := stamp.Clone()
+	if !reflect.DeepEqual(clone, stamp) {
+		t.Fatalf("clone %+v differs from %+v", clone, stamp)
+	}
+	clone.Hashes[0][0] ^= 0xff
+	clone.BootstrapIPs[0] = "1.0.0.1"
+	if stamp.Hashes[0][0] != pk1[0] || stamp.BootstrapIPs[0] != "1.1.1.1" {
+		t.Error("modifying the clone modified the original stamp")
+	}
+}
+
+func TestDecodeDoesNotAlias(t *testing.T) {
+	hash2 := make([]byte, 32)
+	original := ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "example.com", Path: "/dns-query", Hashes: [][]uint8{pk1, hash2}}
+	bin, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp, err := newServerStampFromBinary(bin, &ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp.Hashes[0][0] ^= 0xff
+	stamp.Hashes[0] = append(stamp.Hashes[0], 0xff)
+	if bin[11] != pk1[0] || stamp.Hashes[1][0] != 0 {
+		t.Error("decoded hashes share memory with the binary")
+	}
+
+	stamp, err = ParseBinaryWithOptions(bin, ParseOptions{ZeroCopy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp.Hashes[0] = append(stamp.Hashes[0], 0xff)
+	if stamp.Hashes[1][0] != 0 || bin[43] != 32 {
+		t.Error("appending to a zero-copy hash overwrote the binary")
+	}
+	stamp.Hashes[1][0] = 0xff
+	if bin[44] != 0xff {
+		t.Error("zero-copy hashes don't point into the binary")
+	}
+}
+
+func TestBootstrapIPs_LenientReencoding(t *testing.T) {
+	stampStr := bootstrapTestStamp("dns.example.com", "1.1.1.1")
+	stamp, err := ParseWithOptions(stampStr, ParseOptions{LenientBootstrapIPs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := stamp.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != stampStr {
+		t.Errorf("re-encoded stamp string is %q, but %q expected", text, stampStr)
+	}
+	if _, err := json.Marshal(stamp); err != nil {
+		t.Error(err)
+	}
+	if err := stamp.Validate(); !errors.Is(err, ErrInvalidBootstrapIP) {
+		t.Errorf("expected %v from Validate, got %v", ErrInvalidBootstrapIP, err)
+	}
+
+	var decoded ServerStamp
+	if err := decoded.UnmarshalText(text); !errors.Is(err, ErrInvalidBootstrapIP) {
+		t.Errorf("expected %v, got %v", ErrInvalidBootstrapIP, err)
+	}
+	decoded, err = ParseWithOptions(string(text), ParseOptions{LenientBootstrapIPs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, stamp) {
+		t.Errorf("decoded %+v, expected %+v", decoded, stamp)
+	}
+}
@@ -0,0 +1,60 @@
+package dnsstamps
+
+import "encoding/base64"
+
+// Encode returns the sdns:// form of the stamp, after checking that every
+// field can be represented. Unlike String(), it reports why a stamp cannot
+// be encoded, and it also checks the server address.
+func (stamp *ServerStamp) Encode() (string, error) {
+	bin, err := stamp.AppendBinary(nil)
+	if err != nil {
+		return "", err
+	}
+	return StampScheme + base64.RawURLEncoding.EncodeToString(bin), nil
+}
+
+// AppendBinary appends the binary form of the stamp to b, after checking
+// that every field can be encoded. Stamps decoded by NewServerStampFromString
+// can always be encoded; use Validate() for stricter checks.
+func (stamp *ServerStamp) AppendBinary(b []byte) ([]byte, error) {
+	if err := stamp.checkEncodable(); err != nil {
+		return b, err
+	}
+	bin, err := stamp.bytes()
+	if err != nil {
+		return b, err
+	}
+	return append(b, bin...), nil
+}
+
+const (
+	stampFieldProps = 1 << iota
+	stampFieldAddr
+	stampFieldPk
+	stampFieldHashes
+	stampFieldProviderName
+	stampFieldPath
+	stampFieldBootstrapIPs
+	stampFieldPayload
+)
+
+// stampFields returns the fields that a protocol encodes, the default port
+// of the server address and whether the IP address can be omitted. Stamps
+// of unknown protocols only have a payload.
+func stampFields(proto StampProtoType) (fields int, defaultPort int, allowEmptyIP bool) {
+	switch proto {
+	case StampProtoTypePlain:
+		return stampFieldProps | stampFieldAddr, DefaultDNSPort, false
+	case StampProtoTypeDNSCrypt:
+		return stampFieldProps | stampFieldAddr | stampFieldPk | stampFieldProviderName, DefaultPort, false
+	case StampProtoTypeDoH, StampProtoTypeODoHRelay:
+		return stampFieldProps | stampFieldAddr | stampFieldHashes | stampFieldProviderName | stampFieldPath | stampFieldBootstrapIPs, DefaultPort, false
+	case StampProtoTypeTLS, StampProtoTypeDoQ:
+		return stampFieldProps | stampFieldAddr | stampFieldHashes | stampFieldProviderName | stampFieldBootstrapIPs, DefaultDoTPort, true
+	case StampProtoTypeODoHTarget:
+		return stampFieldProps | stampFieldProviderName | stampFieldPath, DefaultPort, false
+	case StampProtoTypeDNSCryptRelay:
+		return stampFieldAddr, DefaultPort, false
+	}
+	return stampFieldPayload, 0, false
+}
@@ -0,0 +1,144 @@
+package dnsstamps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncode_MatchesString(t *testing.T) {
+	var stamp ServerStamp
+	stamp.Props = ServerInformalPropertyDNSSEC | ServerInformalPropertyNoLog
+	stamp.Proto = StampProtoTypeDoH
+	stamp.ServerAddrStr = "127.0.0.1"
+	stamp.ProviderName = "example.com"
+	stamp.Hashes = [][]uint8{pk1}
+	stamp.Path = "/dns-query"
+	stamp.BootstrapIPs = []string{"1.1.1.1", "2606:4700:4700::1111"}
+
+	stampStr, err := stamp.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stampStr != stamp.String() {
+		t.Errorf("expected %q but got %q", stamp.String(), stampStr)
+	}
+
+	bin, err := stamp.AppendBinary([]byte{0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bin[0] != 0xff || bin[1] != uint8(StampProtoTypeDoH) {
+		t.Errorf("expected the stamp to be appended, got %x", bin)
+	}
+}
+
+func TestEncode_Errors(t *testing.T) {
+	valid := ServerStamp{
+		Proto:         StampProtoTypeTLS,
+		ServerAddrStr: "1.1.1.1",
+		ProviderName:  "cloudflare-dns.com",
+		Hashes:        [][]uint8{pk1},
+	}
+	tests := []struct {
+		name   string
+		modify func(stamp *ServerStamp)
+		field  string
+		err    error
+	}{
+		{"unknown protocol", func(stamp *ServerStamp) { stamp.Proto = 0x42 }, FieldProto, ErrUnsupportedProtocol},
+		{"short hash", func(stamp *ServerStamp) { stamp.Hashes = [][]uint8{{1, 2, 3}} }, FieldHash, ErrHashLength},
+		{"long provider name", func(stamp *ServerStamp) { stamp.ProviderName = strings.Repeat("a", 256) }, FieldProviderName, ErrFieldTooLong},
+		{"bad port", func(stamp *ServerStamp) { stamp.ServerAddrStr = "1.1.1.1:65536" }, FieldAddress, ErrPortRange},
+		{"empty port", func(stamp *ServerStamp) { stamp.ServerAddrStr = "1.1.1.1:" }, FieldAddress, ErrEmptyPort},
+		{"host name", func(stamp *ServerStamp) { stamp.ServerAddrStr = "dns.example.com" }, FieldAddress, ErrInvalidIP},
+		{"path", func(stamp *ServerStamp) { stamp.Path = "/dns-query" }, FieldPath, ErrUnexpectedField},
+		{"relay props", func(stamp *ServerStamp) {
+			*stamp = ServerStamp{Proto: StampProtoTypeDNSCryptRelay, ServerAddrStr: "1.1.1.1", Props: ServerInformalPropertyNoLog}
+		}, FieldProps, ErrUnexpectedField},
+		{"relay public key", func(stamp *ServerStamp) {
+			*stamp = ServerStamp{Proto: StampProtoTypeDNSCryptRelay, ServerAddrStr: "1.1.1.1", ServerPk: pk1}
+		}, FieldPublicKey, ErrUnexpectedField},
+		{"short public key", func(stamp *ServerStamp) {
+			*stamp = ServerStamp{Proto: StampProtoTypeDNSCrypt, ServerAddrStr: "1.1.1.1", ServerPk: pk1[:5], ProviderName: "2.dnscrypt-cert.example.com"}
+		}, FieldPublicKey, ErrPublicKeyLength},
+	}
+	for _, test := range tests {
+		stamp := valid
+		test.modify(&stamp)
+		_, err := stamp.Encode()
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+			continue
+		}
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != test.field {
+			t.Errorf("%s: expected an error on field %q, got %v", test.name, test.field, err)
+		}
+	}
+}
+
+func TestEncode_OptionalAddress(t *testing.T) {
+	stamp := ServerStamp{Proto: StampProtoTypeDoQ, ServerAddrStr: ":8853", ProviderName: "dns.example.com"}
+	if _, err := stamp.Encode(); err != nil {
+		t.Errorf("unexpected error for an address with only a port: %v", err)
+	}
+	stamp = ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com", Path: "/dns-query"}
+	if _, err := stamp.Encode(); err != nil {
+		t.Errorf("unexpected error for a missing address: %v", err)
+	}
+	stamp = ServerStamp{Proto: StampProtoTypePlain}
+	if _, err := stamp.Encode(); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("expected ErrInvalidIP for a missing plain DNS address, got %v", err)
+	}
+}
+
+func TestEncode_NotValidated(t *testing.T) {
+	tests := []struct {
+		name  string
+		stamp ServerStamp
+		err   error
+	}{
+		{"empty path", ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com"}, ErrInvalidPath},
+		{"empty provider name", ServerStamp{Proto: StampProtoTypeDoH, Path: "/dns-query"}, ErrMissingField},
+	}
+	for _, test := range tests {
+		stampStr, err := test.stamp.Encode()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if err := test.stamp.Validate(); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v from Validate, got %v", test.name, test.err, err)
+		}
+		parsedStamp, err := NewServerStampFromString(stampStr)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if _, err := parsedStamp.MarshalText(); err != nil {
+			t.Errorf("%s: a parsed stamp cannot be re-encoded: %v", test.name, err)
+		}
+	}
+}
+
+func TestString_Unencodable(t *testing.T) {
+	stamps := []ServerStamp{
+		{Proto: 0x42},
+		{Proto: StampProtoTypeDoH, ProviderName: strings.Repeat("a", 256), Path: "/dns-query"},
+		{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com", Path: "/" + strings.Repeat("a", 255)},
+		{Proto: StampProtoTypePlain, ServerAddrStr: strings.Repeat("1", 256)},
+	}
+	for _, stamp := range stamps {
+		if s := stamp.String(); s != "" {
+			t.Errorf("%+v: expected an empty string, got %q", stamp, s)
+		}
+		codec, ok := LookupCodec(stamp.Proto)
+		if !ok {
+			continue
+		}
+		if _, err := codec.Encode(&stamp); !errors.Is(err, ErrFieldTooLong) {
+			t.Errorf("%+v: expected %v from the codec, got %v", stamp, ErrFieldTooLong, err)
+		}
+	}
+}
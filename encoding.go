@@ -0,0 +1,120 @@
+package dnsstamps
+
+import (
+	"reflect"
+	"strings"
+)
+
+// MarshalText implements encoding.TextMarshaler, using the sdns:// form.
+// The zero ServerStamp, such as an unset stamp in a configuration struct, is
+// marshaled as an empty text.
+func (stamp ServerStamp) MarshalText() ([]byte, error) {
+	if stamp.isZero() {
+		return []byte{}, nil
+	}
+	stampStr, err := stamp.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(stampStr), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the same
+// input as NewServerStampFromString. An empty text is unmarshaled as the
+// zero ServerStamp.
+func (stamp *ServerStamp) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*stamp = ServerStamp{}
+		return nil
+	}
+	parsed, err := NewServerStampFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*stamp = parsed
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The result is the
+// decoded stamp, without the scheme and the base64 encoding.
+func (stamp ServerStamp) MarshalBinary() ([]byte, error) {
+	return stamp.AppendBinary(nil)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (stamp *ServerStamp) UnmarshalBinary(data []byte) error {
+	parsed, err := newServerStampFromBinary(data, &ParseOptions{})
+	if err != nil {
+		return err
+	}
+	*stamp = parsed
+	return nil
+}
+
+// StampValue is a flag.Value holding a single stamp.
+//
+//	var upstream dnsstamps.ServerStamp
+//	flag.Var(dnsstamps.NewStampValue(&upstream), "upstream", "upstream server stamp")
+type StampValue struct {
+	stamp *ServerStamp
+}
+
+func NewStampValue(stamp *ServerStamp) *StampValue {
+	return &StampValue{stamp: stamp}
+}
+
+func (value *StampValue) Set(s string) error {
+	return value.stamp.UnmarshalText([]byte(s))
+}
+
+func (value *StampValue) String() string {
+	if value == nil || value.stamp == nil || value.stamp.isZero() || !value.stamp.Proto.supported() {
+		return ""
+	}
+	return value.stamp.String()
+}
+
+func (value *StampValue) Get() interface{} {
+	return *value.stamp
+}
+
+// StampListValue is a flag.Value collecting stamps from a repeated flag.
+// Every occurrence of the flag appends a stamp to the list.
+type StampListValue struct {
+	stamps *[]ServerStamp
+}
+
+func NewStampListValue(stamps *[]ServerStamp) *StampListValue {
+	return &StampListValue{stamps: stamps}
+}
+
+func (value *StampListValue) Set(s string) error {
+	var stamp ServerStamp
+	if err := stamp.UnmarshalText([]byte(s)); err != nil {
+		return err
+	}
+	*value.stamps = append(*value.stamps, stamp)
+	return nil
+}
+
+func (value *StampListValue) String() string {
+	if value == nil || value.stamps == nil {
+		return ""
+	}
+	stampStrs := make([]string, 0, len(*value.stamps))
+	for i := range *value.stamps {
+		if stamp := &(*value.stamps)[i]; stamp.Proto.supported() {
+			stampStrs = append(stampStrs, stamp.String())
+		}
+	}
+	return strings.Join(stampStrs, ",")
+}
+
+func (value *StampListValue) Get() interface{} {
+	return *value.stamps
+}
+
+// isZero returns true if no field of the stamp is set.
+func (stamp *ServerStamp) isZero() bool {
+	return reflect.DeepEqual(stamp, &ServerStamp{})
+}
@@ -0,0 +1,116 @@
+package dnsstamps
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+var (
+	_ encoding.TextMarshaler     = ServerStamp{}
+	_ encoding.TextUnmarshaler   = (*ServerStamp)(nil)
+	_ encoding.BinaryMarshaler   = ServerStamp{}
+	_ encoding.BinaryUnmarshaler = (*ServerStamp)(nil)
+	_ flag.Getter                = (*StampValue)(nil)
+	_ flag.Getter                = (*StampListValue)(nil)
+)
+
+func TestTextMarshaling(t *testing.T) {
+	const stampStr = `sdns://AgcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5AtleGFtcGxlLmNvbQovZG5zLXF1ZXJ5`
+
+	var stamp ServerStamp
+	if err := stamp.UnmarshalText([]byte(stampStr)); err != nil {
+		t.Fatal(err)
+	}
+	if stamp.ProviderName != "example.com" || stamp.Path != "/dns-query" {
+		t.Errorf("unexpected stamp %+v", stamp)
+	}
+	text, err := stamp.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != stampStr {
+		t.Errorf("expected %q but got %q", stampStr, text)
+	}
+
+	if err := stamp.UnmarshalText([]byte("sdns://")); err == nil {
+		t.Error("expected an error for an empty stamp")
+	}
+}
+
+func TestBinaryMarshaling(t *testing.T) {
+	var stamp ServerStamp
+	stamp.Proto = StampProtoTypeTLS
+	stamp.ServerAddrStr = "1.1.1.1:8853"
+	stamp.ProviderName = "cloudflare-dns.com"
+	stamp.Hashes = [][]uint8{pk1}
+	stamp.BootstrapIPs = []string{"1.0.0.1"}
+
+	bin, err := stamp.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bin[0] != uint8(StampProtoTypeTLS) {
+		t.Errorf("expected the binary stamp to start with the protocol, got %#x", bin[0])
+	}
+	var parsedStamp ServerStamp
+	if err := parsedStamp.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	if parsedStamp.String() != stamp.String() {
+		t.Errorf("expected %q but got %q", stamp.String(), parsedStamp.String())
+	}
+
+	stamp.Proto = StampProtoType(0x42)
+	if _, err := stamp.MarshalBinary(); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("expected ErrUnsupportedProtocol, got %v", err)
+	}
+	if _, err := stamp.MarshalText(); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("expected ErrUnsupportedProtocol, got %v", err)
+	}
+}
+
+func TestStampFlags(t *testing.T) {
+	const plain1 = `sdns://AAcAAAAAAAAABzguOC44Ljg`
+	const plain2 = `sdns://AAcAAAAAAAAADDguOC44Ljg6ODA1Mw`
+
+	var upstream ServerStamp
+	var servers []ServerStamp
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(NewStampValue(&upstream), "upstream", "upstream stamp")
+	fs.Var(NewStampListValue(&servers), "server", "server stamps")
+	if err := fs.Parse([]string{"-upstream", plain1, "-server", plain1, "-server", plain2}); err != nil {
+		t.Fatal(err)
+	}
+	if upstream.ServerAddrStr != "8.8.8.8:53" {
+		t.Errorf("unexpected upstream %q", upstream.ServerAddrStr)
+	}
+	if len(servers) != 2 || servers[1].ServerAddrStr != "8.8.8.8:8053" {
+		t.Errorf("unexpected servers %+v", servers)
+	}
+	if s := fs.Lookup("server").Value.String(); s != plain1+","+plain2 {
+		t.Errorf("unexpected flag value %q", s)
+	}
+
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse([]string{"-upstream", "https://example.com"}); err == nil {
+		t.Error("expected an error for an invalid stamp")
+	}
+}
+
+func TestStampFlags_Defaults(t *testing.T) {
+	var upstream ServerStamp
+	var servers []ServerStamp
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(NewStampValue(&upstream), "upstream", "upstream stamp")
+	fs.Var(NewStampListValue(&servers), "server", "server stamps")
+	var usage strings.Builder
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	if strings.Contains(usage.String(), "default") {
+		t.Errorf("unexpected default value in usage:\n%s", usage.String())
+	}
+}
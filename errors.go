@@ -0,0 +1,104 @@
+package dnsstamps
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds reported by the stamp parsers. A *ParseError matches its kind
+// with errors.Is, and the kind's message is the error's message.
+var (
+	ErrInvalidScheme        = errors.New("Stamps are expected to start with \"sdns:\"")
+	ErrTooShort             = errors.New("Stamp is too short")
+	ErrUnsupportedProtocol  = errors.New("Unsupported stamp version or protocol")
+	ErrInvalidStamp         = errors.New("Invalid stamp")
+	ErrEmptyPort            = errors.New("Invalid stamp (empty port)")
+	ErrPortRange            = errors.New("Invalid stamp (port range)")
+	ErrInvalidIP            = errors.New("Invalid stamp (IP address)")
+	ErrHashLength           = errors.New("Invalid stamp (certificate hash must be 32 bytes)")
+	ErrGarbageAfterEnd      = errors.New("Invalid stamp (garbage after end)")
+	ErrInvalidEncoding      = errors.New("Invalid stamp encoding")
+	ErrNonCanonical         = errors.New("Invalid stamp (non-canonical encoding)")
+	ErrInvalidBootstrapIP   = errors.New("Invalid stamp (bootstrap IP address)")
+	ErrDuplicateBootstrapIP = errors.New("Invalid stamp (duplicate bootstrap IP address)")
+)
+
+// Error kinds reported when a ServerStamp cannot be encoded.
+var (
+	ErrFieldTooLong    = errors.New("Field is too long")
+	ErrUnexpectedField = errors.New("Field is not supported by the protocol")
+)
+
+// Error kinds reported for stamps combining a relay and a server.
+var (
+	ErrNotRelayedStamp = errors.New("This is not a relay+server stamp")
+	ErrNotRelay        = errors.New("First stamp is not a relay")
+	ErrRelayedRelay    = errors.New("Second stamp is a relay")
+	// ErrIncompatibleRelay is returned when the server doesn't use the
+	// protocol of the relay: a DNSCrypt relay only relays to DNSCrypt servers,
+	// and an ODoH relay only relays to ODoH targets.
+	ErrIncompatibleRelay = errors.New("Relay cannot relay queries to this server")
+)
+
+// Names of the stamp fields, as reported in errors and by Diff.
+const (
+	FieldProto        = "protocol"
+	FieldProps        = "props"
+	FieldAddress      = "address"
+	FieldPort         = "port"
+	FieldPublicKey    = "public key"
+	FieldHash         = "hash"
+	FieldProviderName = "provider name"
+	FieldPath         = "path"
+	FieldBootstrapIP  = "bootstrap IP"
+	FieldPayload      = "payload"
+)
+
+// ParseError describes why a stamp could not be decoded.
+type ParseError struct {
+	// Kind is one of the Err* values above.
+	Kind error
+	// Proto is the protocol being decoded. It is only meaningful if Offset >= 0.
+	Proto StampProtoType
+	// Field is the name of the field being decoded, if any.
+	Field string
+	// Offset is the position in the decoded binary stamp, or -1 if the
+	// error happened before the stamp could be decoded.
+	Offset int
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Kind.Error()
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func newParseError(kind error, proto StampProtoType, field string, offset int) error {
+	return &ParseError{Kind: kind, Proto: proto, Field: field, Offset: offset}
+}
+
+// FieldError describes an invalid field of a ServerStamp.
+type FieldError struct {
+	Proto StampProtoType
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s stamp: %s: %v", e.Proto.String(), e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,87 @@
+package dnsstamps
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestParseError_HashLength(t *testing.T) {
+	var stamp ServerStamp
+	stamp.Proto = StampProtoTypeDoH
+	stamp.ServerAddrStr = "1.1.1.1"
+	stamp.ProviderName = "cloudflare-dns.com"
+	stamp.Path = "/dns-query"
+	stamp.Hashes = [][]uint8{{0x01, 0x02, 0x03}}
+
+	_, err := NewServerStampFromString(stamp.String())
+	if !errors.Is(err, ErrHashLength) {
+		t.Fatalf("expected ErrHashLength, got %v", err)
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *ParseError, got %T", err)
+	}
+	if parseErr.Proto != StampProtoTypeDoH {
+		t.Errorf("expected proto DoH but got %v", parseErr.Proto)
+	}
+	if parseErr.Field != FieldHash {
+		t.Errorf("expected field %q but got %q", FieldHash, parseErr.Field)
+	}
+	// proto(1) + props(8) + addrLen(1) + "1.1.1.1"(7)
+	if parseErr.Offset != 17 {
+		t.Errorf("expected offset 17 but got %d", parseErr.Offset)
+	}
+}
+
+func TestParseError_AddressPort(t *testing.T) {
+	// [DNSSEC|No Filter|No Log] + 8.8.8.8:0
+	bin := []byte{0x00, 0x07, 0, 0, 0, 0, 0, 0, 0, 9}
+	bin = append(bin, "8.8.8.8:0"...)
+	_, err := NewServerStampFromString(StampScheme + base64.RawURLEncoding.EncodeToString(bin))
+	if !errors.Is(err, ErrPortRange) {
+		t.Fatalf("expected ErrPortRange, got %v", err)
+	}
+	if err.Error() != "Invalid stamp (port range)" {
+		t.Errorf("unexpected error message %q", err.Error())
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Field != FieldAddress || parseErr.Offset != 10 {
+		t.Errorf("unexpected parse error %+v", parseErr)
+	}
+}
+
+func TestParseError_Truncated(t *testing.T) {
+	const stamp = `sdns://BQcAAAAAAAAAEG9kb2guZXhhbXBsZS5jb20HL3RhcmdldA`
+	bin, err := base64.RawURLEncoding.DecodeString(stamp[len(StampScheme):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewServerStampFromString(StampScheme + base64.RawURLEncoding.EncodeToString(bin[:len(bin)-3]))
+	if !errors.Is(err, ErrInvalidStamp) {
+		t.Fatalf("expected ErrInvalidStamp, got %v", err)
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Field != FieldPath {
+		t.Errorf("expected an error on the path, got %+v", parseErr)
+	}
+}
+
+func TestParseError_SchemeAndEncoding(t *testing.T) {
+	_, err := NewServerStampFromString("https://example.com")
+	if !errors.Is(err, ErrInvalidScheme) {
+		t.Errorf("expected ErrInvalidScheme, got %v", err)
+	}
+	_, err = NewServerStampFromString("sdns://!!!")
+	if !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("expected ErrInvalidEncoding, got %v", err)
+	}
+	var corrupt base64.CorruptInputError
+	if !errors.As(err, &corrupt) {
+		t.Errorf("expected the base64 error to be wrapped, got %T", err)
+	}
+	_, err = NewServerStampFromString("sdns://cA")
+	if !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("expected ErrUnsupportedProtocol, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package dnsstamps
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type stampJSON struct {
+	Stamp        string         `json:"stamp,omitempty"`
+	Protocol     string         `json:"protocol"`
+	ServerAddr   string         `json:"server_addr,omitempty"`
+	ServerPk     string         `json:"server_pk,omitempty"`
+	Hashes       []string       `json:"hashes,omitempty"`
+	ProviderName string         `json:"provider_name,omitempty"`
+	Path         string         `json:"path,omitempty"`
+	Props        stampPropsJSON `json:"props"`
+	BootstrapIPs []string       `json:"bootstrap_ips,omitempty"`
+}
+
+type stampPropsJSON struct {
+	DNSSEC   bool `json:"dnssec"`
+	NoLog    bool `json:"nolog"`
+	NoFilter bool `json:"nofilter"`
+	// Other holds the property bits that have no name yet
+	Other uint64 `json:"other,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The protocol is represented by its
+// name, the properties as booleans, hashes and public keys as hex strings,
+// and the "stamp" member holds the sdns:// form of the stamp. The zero
+// ServerStamp is marshaled as null.
+func (stamp ServerStamp) MarshalJSON() ([]byte, error) {
+	if stamp.isZero() {
+		return []byte("null"), nil
+	}
+	stampStr, err := stamp.Encode()
+	if err != nil {
+		return nil, err
+	}
+	js := stampJSON{
+		Stamp:        stampStr,
+		Protocol:     stamp.Proto.String(),
+		ServerAddr:   stamp.ServerAddrStr,
+		ServerPk:     hex.EncodeToString(stamp.ServerPk),
+		ProviderName: stamp.ProviderName,
+		Path:         stamp.Path,
+		Props: stampPropsJSON{
+			DNSSEC:   stamp.Props&ServerInformalPropertyDNSSEC != 0,
+			NoLog:    stamp.Props&ServerInformalPropertyNoLog != 0,
+			NoFilter: stamp.Props&ServerInformalPropertyNoFilter != 0,
+			Other:    uint64(stamp.Props &^ (ServerInformalPropertyDNSSEC | ServerInformalPropertyNoLog | ServerInformalPropertyNoFilter)),
+		},
+		BootstrapIPs: stamp.BootstrapIPs,
+	}
+	for _, hash := range stamp.Hashes {
+		js.Hashes = append(js.Hashes, hex.EncodeToString(hash))
+	}
+	return json.Marshal(js)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. If the protocol is missing or
+// unknown, the stamp is decoded from the "stamp" member. Otherwise, it is
+// built from the other members, that must describe a stamp that can be
+// encoded, and that must match the "stamp" member if it is present. Like
+// other unmarshalers, it leaves the stamp unchanged for null.
+func (stamp *ServerStamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var js stampJSON
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	if js.Protocol == "" {
+		if js.Stamp == "" {
+			return errors.New("Missing protocol and stamp")
+		}
+		return stamp.UnmarshalText([]byte(js.Stamp))
+	}
+	proto, ok := stampProtoTypeFromName(js.Protocol)
+	if !ok {
+		if js.Stamp != "" {
+			parsed, err := ParseWithOptions(js.Stamp, ParseOptions{PreserveUnknown: true})
+			if err != nil {
+				return err
+			}
+			*stamp = parsed
+			return nil
+		}
+		return fmt.Errorf("Unsupported protocol: [%s]", js.Protocol)
+	}
+	parsed := ServerStamp{
+		Proto:         proto,
+		ServerAddrStr: js.ServerAddr,
+		ProviderName:  js.ProviderName,
+		Path:          js.Path,
+		Props:         ServerInformalProperties(js.Props.Other),
+		BootstrapIPs:  js.BootstrapIPs,
+	}
+	if js.Props.DNSSEC {
+		parsed.Props |= ServerInformalPropertyDNSSEC
+	}
+	if js.Props.NoLog {
+		parsed.Props |= ServerInformalPropertyNoLog
+	}
+	if js.Props.NoFilter {
+		parsed.Props |= ServerInformalPropertyNoFilter
+	}
+	if js.ServerPk != "" {
+		serverPk, err := hex.DecodeString(js.ServerPk)
+		if err != nil {
+			return fmt.Errorf("Invalid public key: [%s]", js.ServerPk)
+		}
+		parsed.ServerPk = serverPk
+	}
+	for _, hashStr := range js.Hashes {
+		hash, err := hex.DecodeString(hashStr)
+		if err != nil {
+			return fmt.Errorf("Invalid hash: [%s]", hashStr)
+		}
+		parsed.Hashes = append(parsed.Hashes, hash)
+	}
+	if err := parsed.checkEncodable(); err != nil {
+		return err
+	}
+	if js.Stamp != "" {
+		stampFromStr, err := ParseWithOptions(js.Stamp, ParseOptions{LenientBootstrapIPs: true})
+		if err != nil {
+			return err
+		}
+		if !stampFromStr.Equal(&parsed) {
+			return fmt.Errorf("Stamp doesn't match the other members: [%s]", js.Stamp)
+		}
+	}
+	*stamp = parsed
+	return nil
+}
+
+func stampProtoTypeFromName(name string) (StampProtoType, bool) {
+	for i := 0; i <= 0xff; i++ {
+		proto := StampProtoType(i)
+		if proto.supported() && strings.EqualFold(proto.String(), name) {
+			return proto, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,140 @@
+package dnsstamps
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSON_RoundTrip(t *testing.T) {
+	stamps := []string{
+		`sdns://AQcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5BkyLmRuc2NyeXB0LWNlcnQubG9jYWxob3N0`,
+		`sdns://AgcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5AtleGFtcGxlLmNvbQovZG5zLXF1ZXJ5`,
+		`sdns://BQcAAAAAAAAAEG9kb2guZXhhbXBsZS5jb20HL3RhcmdldA`,
+		`sdns://hQcAAAAAAAAAB1s6OjFdOjEgw4Rr8kuek8pkJ0wOxnwezF4CT_ys0tdAGTUOgf5UauQPZG9oLmV4YW1wbGUuY29tBi9yZWxheQ`,
+		`sdns://AAcAAAAAAAAADDguOC44Ljg6ODA1Mw`,
+	}
+	for _, stampStr := range stamps {
+		stamp, err := NewServerStampFromString(stampStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		js, err := json.Marshal(stamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded ServerStamp
+		if err := json.Unmarshal(js, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, stamp) {
+			t.Errorf("round-trip of %s: expected %+v but got %+v", js, stamp, decoded)
+		}
+		if decoded.String() != stampStr {
+			t.Errorf("expected %q but got %q", stampStr, decoded.String())
+		}
+	}
+}
+
+func TestJSON_Representation(t *testing.T) {
+	var stamp ServerStamp
+	stamp.Proto = StampProtoTypeDoH
+	stamp.Props = ServerInformalPropertyDNSSEC | ServerInformalPropertyNoFilter
+	stamp.ServerAddrStr = "127.0.0.1"
+	stamp.ProviderName = "example.com"
+	stamp.Path = "/dns-query"
+	stamp.Hashes = [][]uint8{pk1}
+
+	js, err := json.Marshal(stamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(js, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["protocol"] != "DoH" {
+		t.Errorf("expected protocol DoH but got %v", fields["protocol"])
+	}
+	if fields["stamp"] != stamp.String() {
+		t.Errorf("expected stamp %q but got %v", stamp.String(), fields["stamp"])
+	}
+	hashes, _ := fields["hashes"].([]interface{})
+	if len(hashes) != 1 || hashes[0] != strings.ToLower(strings.Replace(
+		"C3:84:6B:F2:4B:9E:93:CA:64:27:4C:0E:C6:7C:1E:CC:5E:02:4F:FC:AC:D2:D7:40:19:35:0E:81:FE:54:6A:E4", ":", "", -1)) {
+		t.Errorf("unexpected hashes %v", fields["hashes"])
+	}
+	props, _ := fields["props"].(map[string]interface{})
+	if props["dnssec"] != true || props["nolog"] != false || props["nofilter"] != true {
+		t.Errorf("unexpected props %v", fields["props"])
+	}
+}
+
+func TestJSON_StampOnly(t *testing.T) {
+	var stamp ServerStamp
+	if err := json.Unmarshal([]byte(`{"stamp":"sdns://AAcAAAAAAAAABzguOC44Ljg"}`), &stamp); err != nil {
+		t.Fatal(err)
+	}
+	if stamp.Proto != StampProtoTypePlain || stamp.ServerAddrStr != "8.8.8.8:53" {
+		t.Errorf("unexpected stamp %+v", stamp)
+	}
+	if err := json.Unmarshal([]byte(`{"protocol":"carrier pigeon"}`), &stamp); err == nil {
+		t.Error("expected an error for an unknown protocol")
+	}
+}
+
+func TestJSON_Inconsistent(t *testing.T) {
+	var stamp ServerStamp
+	if err := json.Unmarshal([]byte(`{"protocol":"Plain","server_addr":"8.8.8.8","provider_name":"dns.google"}`), &stamp); !errors.Is(err, ErrUnexpectedField) {
+		t.Errorf("expected %v, got %v", ErrUnexpectedField, err)
+	}
+	if err := json.Unmarshal([]byte(`{"protocol":"DoH","provider_name":"dns.example.com","path":"/dns-query","hashes":["0102"]}`), &stamp); !errors.Is(err, ErrHashLength) {
+		t.Errorf("expected %v, got %v", ErrHashLength, err)
+	}
+	props := `"props":{"dnssec":true,"nolog":true,"nofilter":true}`
+	if err := json.Unmarshal([]byte(`{"stamp":"sdns://AAcAAAAAAAAABzguOC44Ljg","protocol":"Plain","server_addr":"9.9.9.9",`+props+`}`), &stamp); err == nil {
+		t.Error("expected an error for a stamp that doesn't match the other members")
+	}
+	if err := json.Unmarshal([]byte(`{"stamp":"sdns://AAcAAAAAAAAABzguOC44Ljg","protocol":"Plain","server_addr":"8.8.8.8:53",`+props+`}`), &stamp); err != nil {
+		t.Fatal(err)
+	}
+	if stamp.ServerAddrStr != "8.8.8.8:53" {
+		t.Errorf("unexpected stamp %+v", stamp)
+	}
+}
+
+func TestJSON_UnsetStamp(t *testing.T) {
+	type config struct {
+		Upstream ServerStamp `json:"upstream"`
+		Fallback ServerStamp `json:"fallback"`
+	}
+	const doh = `sdns://AgcAAAAAAAAABzEuMC4wLjEAEmRucy5jbG91ZGZsYXJlLmNvbQovZG5zLXF1ZXJ5`
+	upstream, err := NewServerStampFromString(doh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(config{Upstream: upstream})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), `"fallback":null`) {
+		t.Errorf("expected an unset stamp to be null, got %s", js)
+	}
+	var decoded config
+	if err := json.Unmarshal(js, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Upstream.String() != doh || !decoded.Fallback.isZero() {
+		t.Errorf("unexpected config %+v", decoded)
+	}
+
+	text, err := decoded.Fallback.MarshalText()
+	if err != nil || len(text) != 0 {
+		t.Errorf("expected an empty text, got %q, %v", text, err)
+	}
+	if err := decoded.Upstream.UnmarshalText(text); err != nil || !decoded.Upstream.isZero() {
+		t.Errorf("expected an empty text to unmarshal as an unset stamp, got %+v, %v", decoded.Upstream, err)
+	}
+}
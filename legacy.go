@@ -0,0 +1,157 @@
+package dnsstamps
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LegacyPublicKeyString returns the public key of the stamp in the format
+// accepted by NewDNSCryptServerStampFromLegacy: uppercase hexadecimal, in
+// groups of two bytes separated by colons, such as "E801:B84E:...".
+func (stamp *ServerStamp) LegacyPublicKeyString() string {
+	hexPk := strings.ToUpper(hex.EncodeToString(stamp.ServerPk))
+	groups := make([]string, 0, (len(hexPk)+3)/4)
+	for len(hexPk) > 4 {
+		groups = append(groups, hexPk[:4])
+		hexPk = hexPk[4:]
+	}
+	if hexPk != "" {
+		groups = append(groups, hexPk)
+	}
+	return strings.Join(groups, ":")
+}
+
+// LegacyResolver is an entry of a dnscrypt-resolvers.csv file. The resolver
+// address, provider name, provider public key, "DNSSEC validation" and "No
+// logs" columns are represented by Stamp.
+type LegacyResolver struct {
+	Name                       string
+	FullName                   string
+	Description                string
+	Location                   string
+	Coordinates                string
+	URL                        string
+	Version                    string
+	Namecoin                   bool
+	ProviderPublicKeyTXTRecord string
+	Stamp                      ServerStamp
+}
+
+var legacyResolversHeader = []string{
+	"Name", "Full name", "Description", "Location", "Coordinates", "URL", "Version",
+	"DNSSEC validation", "No logs", "Namecoin", "Resolver address", "Provider name",
+	"Provider public key", "Provider public key TXT record",
+}
+
+// ReadLegacyResolvers reads a dnscrypt-resolvers.csv file. The first line
+// must be the header, and columns are found by name.
+func ReadLegacyResolvers(r io.Reader) ([]LegacyResolver, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"Name", "Resolver address", "Provider name", "Provider public key"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("Missing column: [%s]", name)
+		}
+	}
+
+	var resolvers []LegacyResolver
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		column := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		var props ServerInformalProperties
+		if legacyBool(column("DNSSEC validation")) {
+			props |= ServerInformalPropertyDNSSEC
+		}
+		if legacyBool(column("No logs")) {
+			props |= ServerInformalPropertyNoLog
+		}
+		stamp, err := NewDNSCryptServerStampFromLegacy(column("Resolver address"), column("Provider public key"), column("Provider name"), props)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		resolvers = append(resolvers, LegacyResolver{
+			Name:                       column("Name"),
+			FullName:                   column("Full name"),
+			Description:                column("Description"),
+			Location:                   column("Location"),
+			Coordinates:                column("Coordinates"),
+			URL:                        column("URL"),
+			Version:                    column("Version"),
+			Namecoin:                   legacyBool(column("Namecoin")),
+			ProviderPublicKeyTXTRecord: column("Provider public key TXT record"),
+			Stamp:                      stamp,
+		})
+	}
+	return resolvers, nil
+}
+
+// WriteLegacyResolvers writes a dnscrypt-resolvers.csv file, header
+// included. Only DNSCrypt stamps can be represented in this format, and
+// their properties other than DNSSEC and no-log are lost.
+func WriteLegacyResolvers(w io.Writer, resolvers []LegacyResolver) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(legacyResolversHeader); err != nil {
+		return err
+	}
+	for _, resolver := range resolvers {
+		stamp := &resolver.Stamp
+		if stamp.Proto != StampProtoTypeDNSCrypt {
+			return fmt.Errorf("%s: %w", resolver.Name, &FieldError{Proto: stamp.Proto, Field: FieldProto, Err: ErrUnsupportedProtocol})
+		}
+		record := []string{
+			resolver.Name,
+			resolver.FullName,
+			resolver.Description,
+			resolver.Location,
+			resolver.Coordinates,
+			resolver.URL,
+			resolver.Version,
+			legacyYesNo(stamp.Props&ServerInformalPropertyDNSSEC != 0),
+			legacyYesNo(stamp.Props&ServerInformalPropertyNoLog != 0),
+			legacyYesNo(resolver.Namecoin),
+			stamp.ServerAddrStr,
+			stamp.ProviderName,
+			stamp.LegacyPublicKeyString(),
+			resolver.ProviderPublicKeyTXTRecord,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func legacyBool(value string) bool {
+	return strings.EqualFold(strings.TrimSpace(value), "yes")
+}
+
+func legacyYesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
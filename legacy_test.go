@@ -0,0 +1,63 @@
+package dnsstamps
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const legacyTestPk = "E801:B84E:A4A9:6E0B:8B52:12FD:3A2E:2EC8:ED1D:2A61:E6AA:3C17:1813:1D12:C6E1:2A31"
+
+func TestLegacyPublicKeyString(t *testing.T) {
+	stamp, err := NewDNSCryptServerStampFromLegacy("127.0.0.1", strings.ToLower(legacyTestPk), "2.dnscrypt-cert.example.com", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := stamp.LegacyPublicKeyString(); s != legacyTestPk {
+		t.Errorf("expected %q, got %q", legacyTestPk, s)
+	}
+}
+
+func TestLegacyResolvers(t *testing.T) {
+	const csvData = `Name,Full name,Description,Location,Coordinates,URL,Version,DNSSEC validation,No logs,Namecoin,Resolver address,Provider name,Provider public key,Provider public key TXT record
+example,"Example, Inc.",An example resolver,Paris,"48.85, 2.35",https://example.com,1,yes,no,no,192.0.2.1:443,2.dnscrypt-cert.example.com,` + legacyTestPk + `,
+example-ipv6,Example IPv6,,,,,1,no,yes,no,[2001:db8::1]:443,2.dnscrypt-cert.example.com,` + legacyTestPk + `,
+`
+	resolvers, err := ReadLegacyResolvers(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolvers) != 2 {
+		t.Fatalf("expected 2 resolvers, got %d", len(resolvers))
+	}
+	if resolvers[0].FullName != "Example, Inc." || resolvers[0].Stamp.ServerAddrStr != "192.0.2.1:443" ||
+		resolvers[0].Stamp.Props != ServerInformalPropertyDNSSEC {
+		t.Errorf("unexpected resolver %+v", resolvers[0])
+	}
+	if resolvers[1].Stamp.ServerAddrStr != "[2001:db8::1]:443" || resolvers[1].Stamp.Props != ServerInformalPropertyNoLog {
+		t.Errorf("unexpected resolver %+v", resolvers[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLegacyResolvers(&buf, resolvers); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != csvData {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+
+	resolvers[0].Stamp = ServerStamp{Proto: StampProtoTypeDoH}
+	if err := WriteLegacyResolvers(&buf, resolvers); err == nil {
+		t.Error("expected an error for a DoH stamp")
+	}
+}
+
+func TestLegacyResolvers_Errors(t *testing.T) {
+	if _, err := ReadLegacyResolvers(strings.NewReader("Name,Resolver address\n")); err == nil {
+		t.Error("expected an error for missing columns")
+	}
+	const csvData = "Name,Resolver address,Provider name,Provider public key\nbad,192.0.2.1,2.dnscrypt-cert.example.com,E801\n"
+	if _, err := ReadLegacyResolvers(strings.NewReader(csvData)); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
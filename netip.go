@@ -0,0 +1,139 @@
+package dnsstamps
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// NewServerStampFromAddrPort returns a stamp for the given protocol, with
+// its server address set to addr.
+func NewServerStampFromAddrPort(proto StampProtoType, addr netip.AddrPort, props ServerInformalProperties) ServerStamp {
+	return ServerStamp{Proto: proto, ServerAddrStr: addr.String(), Props: props}
+}
+
+// AddrPort returns the IP address and the port of the server. The default
+// port of the protocol is used if the address doesn't include a port.
+//
+// DoH, DoT, DoQ and ODoH relay stamps may have no IP address, or only a
+// port, such as ":8853", in which case the error is ErrMissingField.
+func (stamp *ServerStamp) AddrPort() (netip.AddrPort, error) {
+	if stamp.ServerAddrStr == "" {
+		return netip.AddrPort{}, &FieldError{Proto: stamp.Proto, Field: FieldAddress, Err: ErrMissingField}
+	}
+	host, port, err := stamp.splitHostPort(stamp.ServerAddrStr)
+	if err != nil {
+		return netip.AddrPort{}, &FieldError{Proto: stamp.Proto, Field: FieldAddress, Err: err}
+	}
+	if host == "" {
+		return netip.AddrPort{}, &FieldError{Proto: stamp.Proto, Field: FieldAddress, Err: ErrMissingField}
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.AddrPort{}, &FieldError{Proto: stamp.Proto, Field: FieldAddress, Err: ErrInvalidIP}
+	}
+	return netip.AddrPortFrom(ip, port), nil
+}
+
+// Host returns the provider name, without the port it may include.
+func (stamp *ServerStamp) Host() string {
+	host, _, _ := stamp.splitHostPort(stamp.ProviderName)
+	return host
+}
+
+// Port returns the port included in the provider name, or the default port
+// of the protocol if there is none.
+func (stamp *ServerStamp) Port() (uint16, error) {
+	_, port, err := stamp.splitHostPort(stamp.ProviderName)
+	if err != nil {
+		return 0, &FieldError{Proto: stamp.Proto, Field: FieldProviderName, Err: err}
+	}
+	return port, nil
+}
+
+// SNI returns the server name to send in the TLS handshake, or an empty
+// string if the protocol doesn't use TLS or if the provider name is an IP
+// address.
+func (stamp *ServerStamp) SNI() string {
+	switch stamp.Proto {
+	case StampProtoTypeDoH, StampProtoTypeTLS, StampProtoTypeDoQ, StampProtoTypeODoHTarget, StampProtoTypeODoHRelay:
+	default:
+		return ""
+	}
+	host := stamp.Host()
+	if _, err := netip.ParseAddr(host); err == nil {
+		return ""
+	}
+	return host
+}
+
+// BootstrapAddrs returns the bootstrap IP addresses of the stamp.
+func (stamp *ServerStamp) BootstrapAddrs() ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, bootstrapIP := range stamp.BootstrapIPs {
+		addr, err := parseBootstrapIP(bootstrapIP)
+		if err != nil {
+			return nil, &FieldError{Proto: stamp.Proto, Field: FieldBootstrapIP, Err: fmt.Errorf("%w: [%s]", ErrInvalidIP, bootstrapIP)}
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// SetBootstrapAddrs replaces the bootstrap IP addresses of the stamp.
+func (stamp *ServerStamp) SetBootstrapAddrs(addrs []netip.Addr) {
+	stamp.BootstrapIPs = nil
+	for _, addr := range addrs {
+		stamp.BootstrapIPs = append(stamp.BootstrapIPs, addr.String())
+	}
+}
+
+// parseBootstrapIP parses a bootstrap IP address, that may include a port.
+func parseBootstrapIP(bootstrapIP string) (netip.Addr, error) {
+	if addr, err := netip.ParseAddr(bootstrapIP); err == nil {
+		return addr, nil
+	}
+	addrPort, err := netip.ParseAddrPort(bootstrapIP)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addrPort.Addr(), nil
+}
+
+// normalizeBootstrapIP checks that a bootstrap IP address is an IP literal,
+// optionally with a port, and returns its canonical form.
+func normalizeBootstrapIP(bootstrapIP string) (string, error) {
+	if addr, err := netip.ParseAddr(bootstrapIP); err == nil {
+		return addr.String(), nil
+	}
+	addrPort, err := netip.ParseAddrPort(bootstrapIP)
+	if err != nil {
+		return "", err
+	}
+	return addrPort.String(), nil
+}
+
+// splitHostPort splits "host:port", "[ipv6]:port" or "host" into the host,
+// without brackets, and the port, which defaults to the protocol's port.
+func (stamp *ServerStamp) splitHostPort(hostPort string) (string, uint16, error) {
+	colIndex := portIndex(hostPort)
+	if colIndex < 0 {
+		host := strings.TrimSuffix(strings.TrimPrefix(hostPort, "["), "]")
+		codec, ok := LookupCodec(stamp.Proto)
+		if !ok {
+			return host, 0, ErrUnsupportedProtocol
+		}
+		return host, uint16(codec.DefaultPort), nil
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(hostPort[:colIndex], "["), "]")
+	portStr := hostPort[colIndex+1:]
+	if portStr == "" {
+		return host, 0, ErrEmptyPort
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return host, 0, ErrPortRange
+	}
+	return host, uint16(port), nil
+}
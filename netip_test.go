@@ -0,0 +1,116 @@
+package dnsstamps
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestAddrPort(t *testing.T) {
+	tests := []struct {
+		proto    StampProtoType
+		addr     string
+		expected string
+	}{
+		{StampProtoTypePlain, "8.8.8.8", "8.8.8.8:53"},
+		{StampProtoTypeTLS, "1.1.1.1", "1.1.1.1:853"},
+		{StampProtoTypeDoH, "[2606:4700:4700::1111]", "[2606:4700:4700::1111]:443"},
+		{StampProtoTypeDoQ, "[::1]:8853", "[::1]:8853"},
+	}
+	for _, test := range tests {
+		stamp := ServerStamp{Proto: test.proto, ServerAddrStr: test.addr}
+		addrPort, err := stamp.AddrPort()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.addr, err)
+			continue
+		}
+		if addrPort.String() != test.expected {
+			t.Errorf("expected %s but got %s", test.expected, addrPort)
+		}
+	}
+
+	stamp := ServerStamp{Proto: StampProtoTypeTLS, ServerAddrStr: "dns.example.com"}
+	if _, err := stamp.AddrPort(); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("expected ErrInvalidIP, got %v", err)
+	}
+	stamp.ServerAddrStr = "1.1.1.1:0"
+	if _, err := stamp.AddrPort(); !errors.Is(err, ErrPortRange) {
+		t.Errorf("expected ErrPortRange, got %v", err)
+	}
+	stamp.ServerAddrStr = ""
+	if _, err := stamp.AddrPort(); !errors.Is(err, ErrMissingField) {
+		t.Errorf("expected ErrMissingField, got %v", err)
+	}
+
+	parsedStamp, err := NewServerStampFromString(`sdns://BAAAAAAAAAAABTo4ODUzAA9kbnMuZXhhbXBsZS5jb20`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedStamp.ServerAddrStr != ":8853" {
+		t.Fatalf("unexpected address %q", parsedStamp.ServerAddrStr)
+	}
+	if _, err := parsedStamp.AddrPort(); !errors.Is(err, ErrMissingField) || errors.Is(err, ErrInvalidIP) {
+		t.Errorf("expected ErrMissingField for an address with only a port, got %v", err)
+	}
+	if canonical := parsedStamp.Canonical(); canonical.ServerAddrStr != ":8853" {
+		t.Errorf("unexpected canonical address %q", canonical.ServerAddrStr)
+	}
+}
+
+func TestHostPortAndSNI(t *testing.T) {
+	stamp, err := NewServerStampFromString(`sdns://AgYAAAAAAAAACDkuOS45LjEwABJkbnM5LnF1YWQ5Lm5ldDo0NDMKL2Rucy1xdWVyeQ`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.ProviderName != "dns9.quad9.net:443" {
+		t.Fatalf("unexpected provider name %q", stamp.ProviderName)
+	}
+	if host := stamp.Host(); host != "dns9.quad9.net" {
+		t.Errorf("expected host dns9.quad9.net but got %q", host)
+	}
+	if port, err := stamp.Port(); err != nil || port != 443 {
+		t.Errorf("expected port 443 but got %d (%v)", port, err)
+	}
+	if sni := stamp.SNI(); sni != "dns9.quad9.net" {
+		t.Errorf("expected SNI dns9.quad9.net but got %q", sni)
+	}
+
+	stamp = ServerStamp{Proto: StampProtoTypeTLS, ProviderName: "dns.example.com"}
+	if port, err := stamp.Port(); err != nil || port != DefaultDoTPort {
+		t.Errorf("expected the default port but got %d (%v)", port, err)
+	}
+	stamp.ProviderName = "1.1.1.1"
+	if sni := stamp.SNI(); sni != "" {
+		t.Errorf("expected no SNI for an IP address, got %q", sni)
+	}
+	stamp = ServerStamp{Proto: StampProtoTypeDNSCrypt, ProviderName: "2.dnscrypt-cert.example.com"}
+	if sni := stamp.SNI(); sni != "" {
+		t.Errorf("expected no SNI for DNSCrypt, got %q", sni)
+	}
+}
+
+func TestBootstrapAddrs(t *testing.T) {
+	stamp := NewServerStampFromAddrPort(StampProtoTypeTLS, netip.MustParseAddrPort("[2606:4700:4700::1111]:853"), ServerInformalPropertyDNSSEC)
+	if stamp.ServerAddrStr != "[2606:4700:4700::1111]:853" {
+		t.Errorf("unexpected server address %q", stamp.ServerAddrStr)
+	}
+	stamp.ProviderName = "cloudflare-dns.com"
+	stamp.SetBootstrapAddrs([]netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("2606:4700:4700::1001")})
+
+	parsedStamp, err := NewServerStampFromString(stamp.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := parsedStamp.BootstrapAddrs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[0] != netip.MustParseAddr("1.1.1.1") || addrs[1] != netip.MustParseAddr("2606:4700:4700::1001") {
+		t.Errorf("unexpected bootstrap addresses %v", addrs)
+	}
+
+	stamp.BootstrapIPs = []string{"1.1.1.1:53", "dns.example.com"}
+	if _, err := stamp.BootstrapAddrs(); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("expected ErrInvalidIP, got %v", err)
+	}
+}
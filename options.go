@@ -0,0 +1,199 @@
+package dnsstamps
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ParseMode selects how strictly stamp strings are checked.
+type ParseMode int
+
+const (
+	// ParseModeDefault accepts what NewServerStampFromString accepts.
+	ParseModeDefault ParseMode = iota
+	// ParseModeLenient also accepts surrounding and embedded whitespace, an
+	// uppercase scheme, padded or standard base64 and percent-encoded stamps.
+	ParseModeLenient
+	// ParseModeStrict only accepts stamps starting with "sdns://" and encoded
+	// exactly as ServerStamp.String() would encode them: no explicit default
+	// port, no empty hash or bootstrap IP entries...
+	ParseModeStrict
+)
+
+// ParseOptions control how ParseWithOptions decodes stamps.
+type ParseOptions struct {
+	Mode ParseMode
+	// PreserveUnknown makes stamps with an unknown protocol decode as a
+	// ServerStamp with only Proto and Payload set, instead of failing with
+	// ErrUnsupportedProtocol. Such stamps are encoded back as-is.
+	PreserveUnknown bool
+	// PreserveEncoding makes the stamp remember the binary it was decoded
+	// from. As long as its fields are not modified, the stamp is encoded
+	// back to the exact same binary, even if it was not canonical.
+	PreserveEncoding bool
+	// LenientBootstrapIPs keeps bootstrap IP addresses as they are, instead
+	// of rejecting empty, invalid and duplicate entries and normalizing the
+	// others. Such stamps can be encoded again, but Validate() reports their
+	// bootstrap IP addresses, and decoding them again requires this option:
+	// NewServerStampFromString, UnmarshalText and UnmarshalBinary reject
+	// them.
+	LenientBootstrapIPs bool
+	// ZeroCopy makes the public key, the hashes and the payload of decoded
+	// stamps point into the decoded binary instead of being copied. This
+	// saves allocations, but with ParseBinaryWithOptions, these slices share
+	// memory with the binary given to it, that must then be left unchanged.
+	// Use ServerStamp.Clone() to get independent slices.
+	ZeroCopy bool
+}
+
+// slice returns bin[start:end], copied unless ZeroCopy is set. Zero-copy
+// slices are capped, so that appending to them never overwrites the
+// following fields.
+func (opts *ParseOptions) slice(bin []byte, start, end int) []byte {
+	if opts.ZeroCopy {
+		return bin[start:end:end]
+	}
+	return append([]byte{}, bin[start:end]...)
+}
+
+type rawEncoding struct {
+	bin          []byte
+	opts         ParseOptions
+	explicitPort bool
+}
+
+// ParseWithOptions decodes a stamp string, like NewServerStampFromString.
+func ParseWithOptions(stampStr string, opts ParseOptions) (ServerStamp, error) {
+	switch opts.Mode {
+	case ParseModeLenient:
+		var err error
+		if stampStr, err = normalizeStampString(stampStr); err != nil {
+			return ServerStamp{}, err
+		}
+	case ParseModeStrict:
+		if !strings.HasPrefix(stampStr, StampScheme) {
+			return ServerStamp{}, &ParseError{Kind: ErrInvalidScheme, Offset: -1}
+		}
+	}
+	bin, err := decodeStampString(stampStr)
+	if err != nil {
+		return ServerStamp{}, err
+	}
+	return ParseBinaryWithOptions(bin, opts)
+}
+
+// ParseBinaryWithOptions decodes a binary stamp, as returned by
+// ServerStamp.MarshalBinary(). The lenient mode only applies to stamp
+// strings, and is the same as the default mode here.
+func ParseBinaryWithOptions(bin []byte, opts ParseOptions) (ServerStamp, error) {
+	stamp, err := newServerStampFromBinary(bin, &opts)
+	if err != nil {
+		return stamp, err
+	}
+	if opts.Mode == ParseModeStrict && !stamp.Unknown() {
+		if err := checkCanonical(&stamp, bin); err != nil {
+			return ServerStamp{}, err
+		}
+	}
+	if opts.PreserveEncoding && !stamp.Unknown() {
+		stamp.encoding = newRawEncoding(bin, opts)
+	}
+	return stamp, nil
+}
+
+func newRawEncoding(bin []byte, opts ParseOptions) *rawEncoding {
+	encoding := &rawEncoding{bin: append([]byte{}, bin...), opts: opts}
+	encoding.opts.PreserveEncoding = false
+	fields, _, _ := stampFields(StampProtoType(bin[0]))
+	if fields&stampFieldAddr != 0 {
+		pos := 9
+		if fields&stampFieldProps == 0 {
+			pos = 1
+		}
+		length := int(bin[pos])
+		encoding.explicitPort = portIndex(string(bin[pos+1:pos+1+length])) >= 0
+	}
+	return encoding
+}
+
+// matches returns true if the fields of the stamp are still the ones
+// decoded from the binary.
+func (encoding *rawEncoding) matches(stamp *ServerStamp) bool {
+	decoded, err := newServerStampFromBinary(append([]byte{}, encoding.bin...), &encoding.opts)
+	if err != nil {
+		return false
+	}
+	decoded.encoding = stamp.encoding
+	return reflect.DeepEqual(&decoded, stamp)
+}
+
+// OriginalBinary returns the binary the stamp was decoded from, if it was
+// decoded with ParseOptions.PreserveEncoding.
+func (stamp *ServerStamp) OriginalBinary() []byte {
+	if stamp.encoding == nil {
+		return nil
+	}
+	return append([]byte{}, stamp.encoding.bin...)
+}
+
+// ExplicitPort returns true if the server address had a port in the binary
+// the stamp was decoded from, even if it was the default port. It is only
+// known for stamps decoded with ParseOptions.PreserveEncoding.
+func (stamp *ServerStamp) ExplicitPort() bool {
+	return stamp.encoding != nil && stamp.encoding.explicitPort
+}
+
+// Unknown returns true if no codec is registered for the protocol of the
+// stamp.
+func (stamp *ServerStamp) Unknown() bool {
+	return !stamp.Proto.supported()
+}
+
+// normalizeStampString turns a stamp pasted from somewhere else into a
+// string that decodeStampString accepts.
+func normalizeStampString(stampStr string) (string, error) {
+	stampStr = strings.TrimSpace(stampStr)
+	if strings.Contains(stampStr, "%") {
+		unescaped, err := url.PathUnescape(stampStr)
+		if err != nil {
+			return "", &ParseError{Kind: ErrInvalidEncoding, Offset: -1, Err: err}
+		}
+		stampStr = strings.TrimSpace(unescaped)
+	}
+	if len(stampStr) < 5 || !strings.EqualFold(stampStr[:5], "sdns:") {
+		return "", &ParseError{Kind: ErrInvalidScheme, Offset: -1}
+	}
+	stampStr = strings.TrimPrefix(stampStr[5:], "//")
+	stampStr = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r), r == '=':
+			return -1
+		case r == '+':
+			return '-'
+		case r == '/':
+			return '_'
+		}
+		return r
+	}, stampStr)
+	return StampScheme + stampStr, nil
+}
+
+// checkCanonical verifies that a stamp decoded from bin would be encoded
+// back to bin.
+func checkCanonical(stamp *ServerStamp, bin []byte) error {
+	encoded, err := stamp.bytes()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(bin); i++ {
+		if i >= len(encoded) || encoded[i] != bin[i] {
+			return newParseError(ErrNonCanonical, stamp.Proto, "", i)
+		}
+	}
+	if len(encoded) != len(bin) {
+		return newParseError(ErrNonCanonical, stamp.Proto, "", len(bin))
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package dnsstamps
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPreserveUnknown(t *testing.T) {
+	bin := []byte{0x42, 0x07, 0, 0, 0, 0, 0, 0, 0, 3, 'f', 'o', 'o'}
+	stampStr := StampScheme + base64.RawURLEncoding.EncodeToString(bin)
+
+	if _, err := NewServerStampFromString(stampStr); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Fatalf("expected ErrUnsupportedProtocol, got %v", err)
+	}
+
+	stamp, err := ParseWithOptions(stampStr, ParseOptions{PreserveUnknown: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stamp.Unknown() {
+		t.Error("expected the stamp to be unknown")
+	}
+	if stamp.Proto != 0x42 || string(stamp.Payload) != string(bin[1:]) {
+		t.Errorf("unexpected stamp %+v", stamp)
+	}
+	if stamp.String() != stampStr {
+		t.Errorf("expected %q but got %q", stampStr, stamp.String())
+	}
+	encoded, err := stamp.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded != stampStr {
+		t.Errorf("expected %q but got %q", stampStr, encoded)
+	}
+
+	typed, err := FromServerStamp(stamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown, ok := typed.(UnknownStamp); !ok || unknown.Proto() != 0x42 || unknown.String() != stampStr {
+		t.Errorf("unexpected typed stamp %#v", typed)
+	}
+
+	js, err := json.Marshal(stamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ServerStamp
+	if err := json.Unmarshal(js, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != stampStr {
+		t.Errorf("expected %q after a JSON round-trip, got %q", stampStr, decoded.String())
+	}
+
+	stamp.ProviderName = "example.com"
+	if err := stamp.Validate(); !errors.Is(err, ErrUnexpectedField) {
+		t.Errorf("expected ErrUnexpectedField, got %v", err)
+	}
+}
+
+func TestPreserveUnknown_KnownProtocols(t *testing.T) {
+	const stampStr = `sdns://AAcAAAAAAAAABzguOC44Ljg`
+	stamp, err := ParseWithOptions(stampStr, ParseOptions{PreserveUnknown: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.Unknown() || stamp.Payload != nil {
+		t.Errorf("expected a regular stamp, got %+v", stamp)
+	}
+	stamp.Payload = []byte{1}
+	if err := stamp.Validate(); !errors.Is(err, ErrUnexpectedField) {
+		t.Errorf("expected ErrUnexpectedField for a payload, got %v", err)
+	}
+}
+
+func TestParseModeLenient(t *testing.T) {
+	const stampStr = `sdns://AgcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5AtleGFtcGxlLmNvbQovZG5zLXF1ZXJ5`
+	payload := stampStr[len(StampScheme):]
+	bin, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []string{
+		"  " + stampStr + "\n",
+		"SDNS://" + payload,
+		"sdns:" + payload,
+		StampScheme + base64.URLEncoding.EncodeToString(bin),
+		StampScheme + base64.StdEncoding.EncodeToString(bin),
+		"sdns%3A%2F%2F" + payload,
+		StampScheme + payload[:20] + "\n  " + payload[20:],
+	}
+	for _, input := range inputs {
+		stamp, err := ParseWithOptions(input, ParseOptions{Mode: ParseModeLenient})
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", input, err)
+			continue
+		}
+		if stamp.String() != stampStr {
+			t.Errorf("%q: expected %q but got %q", input, stampStr, stamp.String())
+		}
+	}
+	if _, err := NewServerStampFromString(inputs[0]); err == nil {
+		t.Error("expected surrounding whitespace to be rejected by default")
+	}
+	if _, err := ParseWithOptions("https://example.com", ParseOptions{Mode: ParseModeLenient}); !errors.Is(err, ErrInvalidScheme) {
+		t.Errorf("expected ErrInvalidScheme, got %v", err)
+	}
+}
+
+func TestParseModeStrict(t *testing.T) {
+	const stampStr = `sdns://AAcAAAAAAAAABzguOC44Ljg`
+	if _, err := ParseWithOptions(stampStr, ParseOptions{Mode: ParseModeStrict}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseWithOptions("sdns:"+stampStr[len(StampScheme):], ParseOptions{Mode: ParseModeStrict}); !errors.Is(err, ErrInvalidScheme) {
+		t.Errorf("expected ErrInvalidScheme for the short form, got %v", err)
+	}
+
+	// 8.8.8.8:53, with an explicit default port
+	bin := []byte{0x00, 0x07, 0, 0, 0, 0, 0, 0, 0, 10}
+	bin = append(bin, "8.8.8.8:53"...)
+	explicitPort := StampScheme + base64.RawURLEncoding.EncodeToString(bin)
+	if _, err := NewServerStampFromString(explicitPort); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ParseWithOptions(explicitPort, ParseOptions{Mode: ParseModeStrict})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Kind != ErrNonCanonical || parseErr.Offset != 9 {
+		t.Errorf("expected a non-canonical address length, got %v", err)
+	}
+
+	// A DoT stamp with an empty hash followed by a real one
+	bin = []byte{0x03, 0, 0, 0, 0, 0, 0, 0, 0, 7}
+	bin = append(bin, "1.1.1.1"...)
+	bin = append(bin, 0x80, 32)
+	bin = append(bin, pk1...)
+	bin = append(bin, 7)
+	bin = append(bin, "dns.one"...)
+	emptyHash := StampScheme + base64.RawURLEncoding.EncodeToString(bin)
+	if _, err := NewServerStampFromString(emptyHash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseWithOptions(emptyHash, ParseOptions{Mode: ParseModeStrict}); !errors.Is(err, ErrNonCanonical) {
+		t.Errorf("expected ErrNonCanonical for an empty hash entry, got %v", err)
+	}
+}
+
+func TestPreserveEncoding(t *testing.T) {
+	// 8.8.8.8:53, with an explicit default port
+	bin := []byte{0x00, 0x07, 0, 0, 0, 0, 0, 0, 0, 10}
+	bin = append(bin, "8.8.8.8:53"...)
+	stampStr := StampScheme + base64.RawURLEncoding.EncodeToString(bin)
+
+	stamp, err := NewServerStampFromString(stampStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.String() == stampStr || stamp.ExplicitPort() || stamp.OriginalBinary() != nil {
+		t.Error("expected the encoding not to be preserved by default")
+	}
+
+	stamp, err = ParseWithOptions(stampStr, ParseOptions{PreserveEncoding: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.String() != stampStr {
+		t.Errorf("expected %q but got %q", stampStr, stamp.String())
+	}
+	if encoded, err := stamp.Encode(); err != nil || encoded != stampStr {
+		t.Errorf("expected %q but got %q (%v)", stampStr, encoded, err)
+	}
+	if !stamp.ExplicitPort() {
+		t.Error("expected the port to be explicit")
+	}
+	if string(stamp.OriginalBinary()) != string(bin) {
+		t.Errorf("unexpected original binary %x", stamp.OriginalBinary())
+	}
+
+	stamp.Props = ServerInformalPropertyDNSSEC
+	if stamp.String() == stampStr {
+		t.Error("expected a modified stamp to be encoded again")
+	}
+	stamp.Props = ServerInformalPropertyDNSSEC | ServerInformalPropertyNoLog | ServerInformalPropertyNoFilter
+	if stamp.String() != stampStr {
+		t.Error("expected the original encoding once the modification is reverted")
+	}
+}
+
+func TestPreserveEncoding_EmptyHashEntries(t *testing.T) {
+	bin := []byte{0x03, 0, 0, 0, 0, 0, 0, 0, 0, 7}
+	bin = append(bin, "1.1.1.1"...)
+	bin = append(bin, 0x80, 32)
+	bin = append(bin, pk1...)
+	bin = append(bin, 7)
+	bin = append(bin, "dns.one"...)
+	stampStr := StampScheme + base64.RawURLEncoding.EncodeToString(bin)
+
+	stamp, err := ParseWithOptions(stampStr, ParseOptions{PreserveEncoding: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.String() != stampStr {
+		t.Errorf("expected %q but got %q", stampStr, stamp.String())
+	}
+	if stamp.ExplicitPort() {
+		t.Error("expected the port not to be explicit")
+	}
+	stamp.Hashes[0] = append([]byte{}, stamp.Hashes[0]...)
+	stamp.Hashes[0][0] ^= 0xff
+	if stamp.String() == stampStr {
+		t.Error("expected a modified hash to be encoded")
+	}
+}
+
+func TestParseBinaryWithOptions(t *testing.T) {
+	stamp := ServerStamp{Proto: StampProtoTypeDNSCrypt, ServerAddrStr: "127.0.0.1:443", ServerPk: pk1, ProviderName: "2.dnscrypt-cert.example.com"}
+	bin, err := stamp.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedStamp, err := ParseBinaryWithOptions(bin, ParseOptions{Mode: ParseModeStrict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsedStamp.Equal(&stamp) {
+		t.Errorf("decoded %+v, expected %+v", parsedStamp, stamp)
+	}
+
+	nonCanonical := append([]byte{}, bin[:9]...)
+	nonCanonical = append(nonCanonical, 13)
+	nonCanonical = append(nonCanonical, "127.0.0.1:443"...)
+	nonCanonical = append(nonCanonical, bin[9+1+9:]...)
+	if _, err := ParseBinaryWithOptions(nonCanonical, ParseOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseBinaryWithOptions(nonCanonical, ParseOptions{Mode: ParseModeStrict}); !errors.Is(err, ErrNonCanonical) {
+		t.Errorf("expected %v, got %v", ErrNonCanonical, err)
+	}
+}
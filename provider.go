@@ -0,0 +1,158 @@
+package dnsstamps
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// DNSCryptProvider is the identity of a DNSCrypt server operator: a provider
+// name, such as "2.dnscrypt-cert.example.com", and the Ed25519 key that
+// signs the certificates of the provider's resolvers.
+type DNSCryptProvider struct {
+	Name       string
+	PrivateKey ed25519.PrivateKey
+}
+
+// GenerateDNSCryptProvider returns a provider with a new random key.
+func GenerateDNSCryptProvider(name string) (*DNSCryptProvider, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &DNSCryptProvider{Name: name, PrivateKey: privateKey}, nil
+}
+
+// PublicKey returns the public key of the provider, that is the public key
+// of its stamps.
+func (provider *DNSCryptProvider) PublicKey() ed25519.PublicKey {
+	return provider.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+// NewCertificate returns a certificate for the X25519 key of a resolver,
+// valid from notBefore to notAfter, and signed by the provider. The
+// certificate uses the XChacha20Poly1305 construction, its serial number is
+// notBefore as a Unix timestamp, and its client magic is the beginning of
+// the resolver key. Both times must be between 1970 and 2106, the range of
+// the 32-bit timestamps of certificates.
+func (provider *DNSCryptProvider) NewCertificate(resolverPk *ecdh.PublicKey, notBefore, notAfter time.Time) (DNSCryptCertificate, error) {
+	if resolverPk.Curve() != ecdh.X25519() {
+		return DNSCryptCertificate{}, errors.New("The resolver key must be an X25519 key")
+	}
+	if !notBefore.Before(notAfter) {
+		return DNSCryptCertificate{}, errors.New("The validity period of a certificate cannot be empty")
+	}
+	serial, err := certificateTime(notBefore)
+	if err != nil {
+		return DNSCryptCertificate{}, err
+	}
+	cert := DNSCryptCertificate{
+		ESVersion: DNSCryptESVersionXChacha20Poly1305,
+		Serial:    serial,
+		NotBefore: time.Unix(notBefore.Unix(), 0),
+		NotAfter:  time.Unix(notAfter.Unix(), 0),
+	}
+	copy(cert.ResolverPk[:], resolverPk.Bytes())
+	copy(cert.ClientMagic[:], cert.ResolverPk[:])
+	if err := provider.Sign(&cert); err != nil {
+		return DNSCryptCertificate{}, err
+	}
+	return cert, nil
+}
+
+// Sign sets the signature of a certificate. The validity period of the
+// certificate must be representable as 32-bit Unix timestamps.
+func (provider *DNSCryptProvider) Sign(cert *DNSCryptCertificate) error {
+	signedData, err := cert.signedData()
+	if err != nil {
+		return err
+	}
+	copy(cert.Signature[:], ed25519.Sign(provider.PrivateKey, signedData))
+	return nil
+}
+
+// ServerStamp returns the stamp of a resolver of the provider. The default
+// port is added to serverAddrStr if it is an IP address without a port.
+func (provider *DNSCryptProvider) ServerStamp(serverAddrStr string, props ServerInformalProperties) (ServerStamp, error) {
+	if addr, err := netip.ParseAddr(serverAddrStr); err == nil {
+		serverAddrStr = netip.AddrPortFrom(addr, DefaultPort).String()
+	}
+	stamp := ServerStamp{
+		Proto:         StampProtoTypeDNSCrypt,
+		Props:         props,
+		ServerAddrStr: serverAddrStr,
+		ServerPk:      append([]uint8{}, provider.PublicKey()...),
+		ProviderName:  provider.Name,
+	}
+	if err := stamp.Validate(); err != nil {
+		return ServerStamp{}, err
+	}
+	return stamp, nil
+}
+
+// MarshalDNSCryptProviderKey returns the key file of a provider: a PEM
+// "PRIVATE KEY" block holding the Ed25519 private key in PKCS #8 form, as
+// written by "openssl genpkey -algorithm ed25519".
+func MarshalDNSCryptProviderKey(privateKey ed25519.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ParseDNSCryptProviderKey parses a key file written by
+// MarshalDNSCryptProviderKey.
+func ParseDNSCryptProviderKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("No PEM \"PRIVATE KEY\" block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported key type: %T", key)
+	}
+	return privateKey, nil
+}
+
+// LoadDNSCryptProvider reads the key of a provider from a key file.
+func LoadDNSCryptProvider(name, path string) (*DNSCryptProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := ParseDNSCryptProviderKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &DNSCryptProvider{Name: name, PrivateKey: privateKey}, nil
+}
+
+// SaveKey writes the key of the provider to a new key file, only readable
+// by its owner.
+func (provider *DNSCryptProvider) SaveKey(path string) error {
+	data, err := MarshalDNSCryptProviderKey(provider.PrivateKey)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
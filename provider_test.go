@@ -0,0 +1,96 @@
+package dnsstamps
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDNSCryptProvider(t *testing.T) {
+	provider, err := GenerateDNSCryptProvider("2.dnscrypt-cert.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp, err := provider.ServerStamp("192.0.2.1", ServerInformalPropertyNoLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.ServerAddrStr != "192.0.2.1:443" || string(stamp.ServerPk) != string(provider.PublicKey()) {
+		t.Errorf("unexpected stamp %+v", stamp)
+	}
+	if _, err := NewServerStampFromString(stamp.String()); err != nil {
+		t.Error(err)
+	}
+
+	resolverKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notBefore := time.Now().Add(-time.Minute)
+	cert, err := provider.NewCertificate(resolverKey.PublicKey(), notBefore, notBefore.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, err := cert.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := VerifyDNSCryptCertificate(stamp, bin, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.ResolverPk != cert.ResolverPk || verified.Serial != uint32(notBefore.Unix()) {
+		t.Errorf("unexpected certificate %+v", verified)
+	}
+
+	if _, err := provider.NewCertificate(resolverKey.PublicKey(), notBefore, notBefore); err == nil {
+		t.Error("expected an error for an empty validity period")
+	}
+	if _, err := provider.NewCertificate(resolverKey.PublicKey(), time.Unix(-1, 0), notBefore); !errors.Is(err, ErrInvalidCertificate) {
+		t.Errorf("expected %v for a certificate valid before 1970, got %v", ErrInvalidCertificate, err)
+	}
+	if _, err := provider.NewCertificate(resolverKey.PublicKey(), notBefore, time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrInvalidCertificate) {
+		t.Errorf("expected %v for a certificate valid after 2106, got %v", ErrInvalidCertificate, err)
+	}
+	cert.NotAfter = time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := provider.Sign(&cert); !errors.Is(err, ErrInvalidCertificate) {
+		t.Errorf("expected %v when signing a certificate valid after 2106, got %v", ErrInvalidCertificate, err)
+	}
+	if _, err := cert.MarshalBinary(); !errors.Is(err, ErrInvalidCertificate) {
+		t.Errorf("expected %v when encoding a certificate valid after 2106, got %v", ErrInvalidCertificate, err)
+	}
+	p256Key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.NewCertificate(p256Key.PublicKey(), notBefore, notBefore.Add(time.Hour)); err == nil {
+		t.Error("expected an error for a P-256 resolver key")
+	}
+}
+
+func TestDNSCryptProviderKeyFile(t *testing.T) {
+	provider, err := GenerateDNSCryptProvider("2.dnscrypt-cert.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "provider.key")
+	if err := provider.SaveKey(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.SaveKey(path); err == nil {
+		t.Error("an existing key file was overwritten")
+	}
+	loaded, err := LoadDNSCryptProvider(provider.Name, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.PrivateKey.Equal(provider.PrivateKey) {
+		t.Error("the loaded key differs from the saved one")
+	}
+	if _, err := ParseDNSCryptProviderKey([]byte("not PEM")); err == nil {
+		t.Error("expected an error for an invalid key file")
+	}
+}
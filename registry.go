@@ -0,0 +1,95 @@
+package dnsstamps
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Codec decodes and encodes the stamps of a protocol.
+type Codec struct {
+	// Name is the name of the protocol, as returned by StampProtoType.String()
+	Name string
+	// DefaultPort is the port of the server address when none is given
+	DefaultPort int
+	// Decode decodes a binary stamp, including its protocol identifier
+	Decode func(bin []byte) (ServerStamp, error)
+	// Encode returns the binary form of a stamp, including its protocol identifier
+	Encode func(stamp *ServerStamp) ([]byte, error)
+	// Validate checks a stamp for ServerStamp.Validate(). Optional.
+	Validate func(stamp *ServerStamp) error
+
+	decode func(bin []byte, opts *ParseOptions) (ServerStamp, error)
+}
+
+var (
+	codecsLock sync.RWMutex
+	codecs     = map[StampProtoType]Codec{
+		StampProtoTypePlain:         builtinCodec("Plain", DefaultDNSPort, newPlainDNSServerStamp, (*ServerStamp).plainBytes),
+		StampProtoTypeDNSCrypt:      builtinCodec("DNSCrypt", DefaultPort, newDNSCryptServerStamp, (*ServerStamp).dnsCryptBytes),
+		StampProtoTypeDoH:           builtinCodec("DoH", DefaultPort, newDoHServerStamp, (*ServerStamp).dohBytes),
+		StampProtoTypeTLS:           builtinCodec("TLS", DefaultDoTPort, newDoTServerStamp, (*ServerStamp).dotBytes),
+		StampProtoTypeDoQ:           builtinCodec("QUIC", DefaultDoTPort, newDoQServerStamp, (*ServerStamp).doqBytes),
+		StampProtoTypeODoHTarget:    builtinCodec("oDoH target", DefaultPort, newODoHTargetStamp, (*ServerStamp).oDohTargetBytes),
+		StampProtoTypeDNSCryptRelay: builtinCodec("DNSCrypt relay", DefaultPort, newDNSCryptRelayStamp, (*ServerStamp).dnsCryptRelayBytes),
+		StampProtoTypeODoHRelay:     builtinCodec("oDoH relay", DefaultPort, newODoHRelayStamp, (*ServerStamp).oDohRelayBytes),
+	}
+)
+
+// builtinCodec returns a codec whose decoder also honors the ParseOptions
+// given to ParseWithOptions, and whose encoder refuses fields that are too
+// long instead of truncating them.
+func builtinCodec(name string, defaultPort int, decode func(bin []byte, opts *ParseOptions) (ServerStamp, error), encode func(stamp *ServerStamp) ([]byte, error)) Codec {
+	return Codec{
+		Name:        name,
+		DefaultPort: defaultPort,
+		Decode: func(bin []byte) (ServerStamp, error) {
+			return decode(bin, &ParseOptions{})
+		},
+		Encode: func(stamp *ServerStamp) ([]byte, error) {
+			if errs := fieldLengthErrors(stamp); len(errs) > 0 {
+				return nil, errors.Join(errs...)
+			}
+			return encode(stamp)
+		},
+		Validate: validateFields,
+		decode:   decode,
+	}
+}
+
+// RegisterCodec adds support for a new protocol to NewServerStampFromString
+// and ServerStamp.String(). The built-in protocols cannot be replaced.
+func RegisterCodec(proto StampProtoType, codec Codec) error {
+	if codec.Decode == nil || codec.Encode == nil {
+		return errors.New("A codec requires a decoder and an encoder")
+	}
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	if _, ok := codecs[proto]; ok {
+		return fmt.Errorf("A codec is already registered for protocol 0x%02x", uint8(proto))
+	}
+	codecs[proto] = codec
+	return nil
+}
+
+// LookupCodec returns the codec registered for a protocol.
+func LookupCodec(proto StampProtoType) (Codec, bool) {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	codec, ok := codecs[proto]
+	return codec, ok
+}
+
+func (stampProtoType StampProtoType) supported() bool {
+	_, ok := LookupCodec(stampProtoType)
+	return ok
+}
+
+func (stampProtoType StampProtoType) builtin() bool {
+	switch stampProtoType {
+	case StampProtoTypePlain, StampProtoTypeDNSCrypt, StampProtoTypeDoH, StampProtoTypeTLS,
+		StampProtoTypeDoQ, StampProtoTypeODoHTarget, StampProtoTypeDNSCryptRelay, StampProtoTypeODoHRelay:
+		return true
+	}
+	return false
+}
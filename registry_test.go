@@ -0,0 +1,79 @@
+package dnsstamps
+
+import (
+	"errors"
+	"testing"
+)
+
+// A protocol whose stamps only carry a provider name: id(u8)=0x7e hostName
+const testProtoType = StampProtoType(0x7e)
+
+func init() {
+	err := RegisterCodec(testProtoType, Codec{
+		Name:        "Test",
+		DefaultPort: 5353,
+		Decode: func(bin []byte) (ServerStamp, error) {
+			return ServerStamp{Proto: testProtoType, ProviderName: string(bin[1:])}, nil
+		},
+		Encode: func(stamp *ServerStamp) ([]byte, error) {
+			if stamp.ProviderName == "" {
+				return nil, errors.New("Missing provider name")
+			}
+			return append([]byte{uint8(testProtoType)}, stamp.ProviderName...), nil
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestRegistry_BuiltinCodecs(t *testing.T) {
+	for _, proto := range []StampProtoType{
+		StampProtoTypePlain, StampProtoTypeDNSCrypt, StampProtoTypeDoH, StampProtoTypeTLS,
+		StampProtoTypeDoQ, StampProtoTypeODoHTarget, StampProtoTypeDNSCryptRelay, StampProtoTypeODoHRelay,
+	} {
+		codec, ok := LookupCodec(proto)
+		if !ok {
+			t.Errorf("no codec for built-in protocol %v", proto)
+			continue
+		}
+		if codec.Name != proto.String() {
+			t.Errorf("expected name %q but got %q", proto.String(), codec.Name)
+		}
+		if err := RegisterCodec(proto, codec); err == nil {
+			t.Errorf("expected an error when replacing the %v codec", proto)
+		}
+	}
+	if codec, _ := LookupCodec(StampProtoTypeTLS); codec.DefaultPort != DefaultDoTPort {
+		t.Errorf("expected default port %d but got %d", DefaultDoTPort, codec.DefaultPort)
+	}
+}
+
+func TestRegistry_CustomCodec(t *testing.T) {
+	stamp := ServerStamp{Proto: testProtoType, ProviderName: "internal.example.com"}
+	if name := stamp.Proto.String(); name != "Test" {
+		t.Errorf("expected protocol name Test but got %q", name)
+	}
+	stampStr, err := stamp.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stampStr != stamp.String() {
+		t.Errorf("expected %q but got %q", stamp.String(), stampStr)
+	}
+	parsedStamp, err := NewServerStampFromString(stampStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedStamp.Proto != testProtoType || parsedStamp.ProviderName != "internal.example.com" {
+		t.Errorf("unexpected stamp %+v", parsedStamp)
+	}
+
+	stamp.ProviderName = ""
+	if _, err := stamp.Encode(); err == nil {
+		t.Error("expected the codec error to be returned")
+	}
+	if err := RegisterCodec(0x7f, Codec{Name: "Incomplete"}); err == nil {
+		t.Error("expected an error for a codec without a decoder")
+	}
+}
@@ -0,0 +1,206 @@
+package dnsstamps
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RelayedStamp is a server stamp, reached through a DNSCrypt or an ODoH
+// relay. Its string form is "sdns://<relay>/<server>".
+type RelayedStamp struct {
+	Relay  ServerStamp
+	Server ServerStamp
+}
+
+type relayedStampJSON struct {
+	Stamp  string           `json:"stamp,omitempty"`
+	Relay  *json.RawMessage `json:"relay,omitempty"`
+	Server *json.RawMessage `json:"server,omitempty"`
+}
+
+// NewRelayedStampFromString parses a "sdns://<relay>/<server>" string. The
+// short "sdns:<relay>/<server>" form is also accepted.
+func NewRelayedStampFromString(stampStr string) (RelayedStamp, error) {
+	stamps, err := parseStampChain(stampStr)
+	if err != nil {
+		return RelayedStamp{}, err
+	}
+	if len(stamps) != 2 {
+		return RelayedStamp{}, ErrNotRelayedStamp
+	}
+	relayed := RelayedStamp{Relay: stamps[0], Server: stamps[1]}
+	if err := checkRelayPair(&relayed.Relay, &relayed.Server); err != nil {
+		return RelayedStamp{}, err
+	}
+	return relayed, nil
+}
+
+// Validate checks both stamps, and that the relay can relay queries to the
+// server.
+func (relayed *RelayedStamp) Validate() error {
+	if err := checkRelayPair(&relayed.Relay, &relayed.Server); err != nil {
+		return err
+	}
+	if err := relayed.Relay.Validate(); err != nil {
+		return err
+	}
+	return relayed.Server.Validate()
+}
+
+// Encode returns the "sdns://<relay>/<server>" form of the stamp, after
+// checking that the relay can relay queries to the server, and that both
+// stamps can be encoded. See ServerStamp.Encode().
+func (relayed *RelayedStamp) Encode() (string, error) {
+	if err := checkRelayPair(&relayed.Relay, &relayed.Server); err != nil {
+		return "", err
+	}
+	relay, err := relayed.Relay.Encode()
+	if err != nil {
+		return "", err
+	}
+	server, err := relayed.Server.Encode()
+	if err != nil {
+		return "", err
+	}
+	return relay + "/" + strings.TrimPrefix(server, StampScheme), nil
+}
+
+// String returns the "sdns://<relay>/<server>" form of the stamp, without
+// checking the pair, or an empty string if a stamp cannot be encoded. See
+// ServerStamp.String().
+func (relayed *RelayedStamp) String() string {
+	relay, server := relayed.Relay.String(), relayed.Server.String()
+	if relay == "" || server == "" {
+		return ""
+	}
+	return relay + "/" + strings.TrimPrefix(server, StampScheme)
+}
+
+// MarshalText implements encoding.TextMarshaler, using the
+// "sdns://<relay>/<server>" form.
+func (relayed RelayedStamp) MarshalText() ([]byte, error) {
+	stampStr, err := relayed.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(stampStr), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the same
+// input as NewRelayedStampFromString.
+func (relayed *RelayedStamp) UnmarshalText(text []byte) error {
+	parsed, err := NewRelayedStampFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*relayed = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The "stamp" member holds the
+// combined string, and the "relay" and "server" members hold the JSON form
+// of each stamp.
+func (relayed RelayedStamp) MarshalJSON() ([]byte, error) {
+	stampStr, err := relayed.Encode()
+	if err != nil {
+		return nil, err
+	}
+	relay, err := json.Marshal(relayed.Relay)
+	if err != nil {
+		return nil, err
+	}
+	server, err := json.Marshal(relayed.Server)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(relayedStampJSON{
+		Stamp:  stampStr,
+		Relay:  (*json.RawMessage)(&relay),
+		Server: (*json.RawMessage)(&server),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The "relay" and "server"
+// members are used if both are present, and the "stamp" member otherwise.
+// A JSON null leaves the stamp unchanged.
+func (relayed *RelayedStamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var js relayedStampJSON
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	if js.Relay == nil || js.Server == nil {
+		return relayed.UnmarshalText([]byte(js.Stamp))
+	}
+	var parsed RelayedStamp
+	if err := json.Unmarshal(*js.Relay, &parsed.Relay); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(*js.Server, &parsed.Server); err != nil {
+		return err
+	}
+	if err := checkRelayPair(&parsed.Relay, &parsed.Server); err != nil {
+		return err
+	}
+	*relayed = parsed
+	return nil
+}
+
+func (stampProtoType StampProtoType) relay() bool {
+	_, ok := stampProtoType.relayedProto()
+	return ok
+}
+
+// parseStampChain parses the "/"-separated stamps of a "sdns://" or "sdns:"
+// string.
+func parseStampChain(stampStr string) ([]ServerStamp, error) {
+	if !strings.HasPrefix(stampStr, "sdns:") {
+		return nil, &ParseError{Kind: ErrInvalidScheme, Offset: -1}
+	}
+	stampStr = strings.TrimPrefix(stampStr[5:], "//")
+	var stamps []ServerStamp
+	for _, part := range strings.Split(stampStr, "/") {
+		stamp, err := NewServerStampFromString(StampScheme + part)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, stamp)
+	}
+	return stamps, nil
+}
+
+// relayedProto returns the protocol of the servers a relay can relay
+// queries to.
+func (stampProtoType StampProtoType) relayedProto() (StampProtoType, bool) {
+	switch stampProtoType {
+	case StampProtoTypeDNSCryptRelay:
+		return StampProtoTypeDNSCrypt, true
+	case StampProtoTypeODoHRelay:
+		return StampProtoTypeODoHTarget, true
+	}
+	return 0, false
+}
+
+// CanRelay returns true if relay is a relay that can relay queries to
+// server.
+func CanRelay(relay, server ServerStamp) bool {
+	return checkRelayPair(&relay, &server) == nil
+}
+
+// checkRelayPair checks that the first stamp is a relay, that the second one
+// is not, and that the relay can relay queries to the server.
+func checkRelayPair(relay, server *ServerStamp) error {
+	if !relay.Proto.relay() {
+		return ErrNotRelay
+	}
+	if server.Proto.relay() {
+		return ErrRelayedRelay
+	}
+	if proto, _ := relay.Proto.relayedProto(); server.Proto != proto {
+		return fmt.Errorf("%w: %s -> %s", ErrIncompatibleRelay, relay.Proto.String(), server.Proto.String())
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package dnsstamps
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const relayedTestStamp = `sdns://hQcAAAAAAAAAB1s6OjFdOjEgw4Rr8kuek8pkJ0wOxnwezF4CT_ys0tdAGTUOgf5UauQPZG9oLmV4YW1wbGUuY29tBi9yZWxheQ/BQEAAAAAAAAAEG9kb2guZXhhbXBsZS5jb20HL3RhcmdldA`
+
+func TestRelayedStamp(t *testing.T) {
+	relayed, err := NewRelayedStampFromString(relayedTestStamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relayed.Relay.Proto != StampProtoTypeODoHRelay || relayed.Server.Proto != StampProtoTypeODoHTarget {
+		t.Errorf("unexpected protocols %v and %v", relayed.Relay.Proto, relayed.Server.Proto)
+	}
+	if s := relayed.String(); s != relayedTestStamp {
+		t.Errorf("re-parsed stamp string is %q, but %q expected", s, relayedTestStamp)
+	}
+
+	short, err := NewRelayedStampFromString("sdns:" + relayedTestStamp[7:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(short, relayed) {
+		t.Errorf("short form decoded as %+v, expected %+v", short, relayed)
+	}
+}
+
+func TestRelayedStamp_Errors(t *testing.T) {
+	server := relayedTestStamp[strings.LastIndex(relayedTestStamp, "/")+1:]
+	tests := []struct {
+		stampStr string
+		err      error
+	}{
+		{"https://example.com", ErrInvalidScheme},
+		{relayedTestStamp + "/" + server, ErrNotRelayedStamp},
+		{StampScheme + server + "/" + server, ErrNotRelay},
+	}
+	for _, test := range tests {
+		if _, err := NewRelayedStampFromString(test.stampStr); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.stampStr, test.err, err)
+		}
+	}
+}
+
+func TestRelayedStamp_Marshal(t *testing.T) {
+	relayed, err := NewRelayedStampFromString(relayedTestStamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := relayed.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != relayedTestStamp {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	js, err := json.Marshal(relayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded RelayedStamp
+	if err := json.Unmarshal(js, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != relayedTestStamp {
+		t.Errorf("JSON round trip gave %q", decoded.String())
+	}
+
+	decoded = RelayedStamp{}
+	if err := json.Unmarshal([]byte(`{"stamp":"`+relayedTestStamp+`"}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, relayed) {
+		t.Errorf("decoded %+v, expected %+v", decoded, relayed)
+	}
+
+	var config struct {
+		Route RelayedStamp `json:"route"`
+	}
+	if err := json.Unmarshal([]byte(`{"route":null}`), &config); err != nil {
+		t.Errorf("unexpected error for a null stamp: %v", err)
+	}
+	if !reflect.DeepEqual(config.Route, RelayedStamp{}) {
+		t.Errorf("a null stamp decoded as %+v", config.Route)
+	}
+
+	relayed.Relay, relayed.Server = relayed.Server, relayed.Relay
+	if _, err := json.Marshal(relayed); !errors.Is(err, ErrNotRelay) {
+		t.Errorf("expected %v, got %v", ErrNotRelay, err)
+	}
+}
+
+func TestCanRelay(t *testing.T) {
+	dnsCryptRelay := ServerStamp{Proto: StampProtoTypeDNSCryptRelay, ServerAddrStr: "1.1.1.1:443"}
+	oDoHRelay := ServerStamp{Proto: StampProtoTypeODoHRelay, ProviderName: "relay.example.com", Path: "/relay"}
+	dnsCrypt := ServerStamp{Proto: StampProtoTypeDNSCrypt, ServerAddrStr: "1.0.0.1:443", ServerPk: pk1, ProviderName: "2.dnscrypt-cert.example.com"}
+	oDoHTarget := ServerStamp{Proto: StampProtoTypeODoHTarget, ProviderName: "target.example.com", Path: "/target"}
+	doh := ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "doh.example.com", Path: "/dns-query"}
+	plain := ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: "8.8.8.8:53"}
+
+	tests := []struct {
+		relay, server ServerStamp
+		err           error
+	}{
+		{dnsCryptRelay, dnsCrypt, nil},
+		{oDoHRelay, oDoHTarget, nil},
+		{dnsCryptRelay, oDoHTarget, ErrIncompatibleRelay},
+		{dnsCryptRelay, plain, ErrIncompatibleRelay},
+		{dnsCryptRelay, doh, ErrIncompatibleRelay},
+		{oDoHRelay, dnsCrypt, ErrIncompatibleRelay},
+		{oDoHRelay, oDoHRelay, ErrRelayedRelay},
+		{doh, oDoHTarget, ErrNotRelay},
+	}
+	for _, test := range tests {
+		if canRelay := CanRelay(test.relay, test.server); canRelay != (test.err == nil) {
+			t.Errorf("CanRelay(%v, %v) = %v", test.relay.Proto, test.server.Proto, canRelay)
+		}
+		stampStr := test.relay.String() + "/" + test.server.String()[len(StampScheme):]
+		if _, _, err := NewRelayAndServerStampFromString(stampStr); !errors.Is(err, test.err) {
+			t.Errorf("%v -> %v: expected %v, got %v", test.relay.Proto, test.server.Proto, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package dnsstamps
+
+// Stamp is implemented by the protocol-specific stamp types.
+type Stamp interface {
+	Proto() StampProtoType
+	Props() ServerInformalProperties
+	String() string
+	// ServerStamp converts the stamp to the generic representation.
+	ServerStamp() ServerStamp
+}
+
+type PlainStamp struct {
+	Properties    ServerInformalProperties
+	ServerAddrStr string
+}
+
+type DNSCryptStamp struct {
+	Properties    ServerInformalProperties
+	ServerAddrStr string
+	ServerPk      []uint8
+	ProviderName  string
+}
+
+type DoHStamp struct {
+	Properties    ServerInformalProperties
+	ServerAddrStr string
+	Hashes        [][]uint8
+	ProviderName  string
+	Path          string
+	BootstrapIPs  []string
+}
+
+type DoTStamp struct {
+	Properties    ServerInformalProperties
+	ServerAddrStr string
+	Hashes        [][]uint8
+	ProviderName  string
+	BootstrapIPs  []string
+}
+
+type DoQStamp struct {
+	Properties    ServerInformalProperties
+	ServerAddrStr string
+	Hashes        [][]uint8
+	ProviderName  string
+	BootstrapIPs  []string
+}
+
+type ODoHTargetStamp struct {
+	Properties   ServerInformalProperties
+	ProviderName string
+	Path         string
+}
+
+// DNSCryptRelayStamp has no properties: they are not part of the encoding.
+type DNSCryptRelayStamp struct {
+	ServerAddrStr string
+}
+
+type ODoHRelayStamp struct {
+	Properties    ServerInformalProperties
+	ServerAddrStr string
+	Hashes        [][]uint8
+	ProviderName  string
+	Path          string
+	BootstrapIPs  []string
+}
+
+// UnknownStamp is a stamp of an unknown protocol, as decoded with
+// ParseOptions.PreserveUnknown.
+type UnknownStamp struct {
+	Protocol StampProtoType
+	Payload  []byte
+}
+
+// ParseStamp decodes a stamp string into the type matching its protocol.
+func ParseStamp(stampStr string) (Stamp, error) {
+	stamp, err := NewServerStampFromString(stampStr)
+	if err != nil {
+		return nil, err
+	}
+	return FromServerStamp(stamp)
+}
+
+// FromServerStamp converts a ServerStamp into the type matching its
+// protocol. Fields the protocol doesn't have must be empty, so that the
+// conversion is lossless.
+func FromServerStamp(stamp ServerStamp) (Stamp, error) {
+	if !stamp.Proto.builtin() && (stamp.Proto.supported() || stamp.Payload == nil) {
+		return nil, &FieldError{Proto: stamp.Proto, Field: FieldProto, Err: ErrUnsupportedProtocol}
+	}
+	if fields := stamp.unexpectedFields(); len(fields) > 0 {
+		return nil, &FieldError{Proto: stamp.Proto, Field: fields[0], Err: ErrUnexpectedField}
+	}
+	if !stamp.Proto.builtin() {
+		return UnknownStamp{Protocol: stamp.Proto, Payload: stamp.Payload}, nil
+	}
+
+	switch stamp.Proto {
+	case StampProtoTypePlain:
+		return PlainStamp{Properties: stamp.Props, ServerAddrStr: stamp.ServerAddrStr}, nil
+	case StampProtoTypeDNSCrypt:
+		return DNSCryptStamp{Properties: stamp.Props, ServerAddrStr: stamp.ServerAddrStr, ServerPk: stamp.ServerPk, ProviderName: stamp.ProviderName}, nil
+	case StampProtoTypeDoH:
+		return DoHStamp{Properties: stamp.Props, ServerAddrStr: stamp.ServerAddrStr, Hashes: stamp.Hashes, ProviderName: stamp.ProviderName, Path: stamp.Path, BootstrapIPs: stamp.BootstrapIPs}, nil
+	case StampProtoTypeTLS:
+		return DoTStamp{Properties: stamp.Props, ServerAddrStr: stamp.ServerAddrStr, Hashes: stamp.Hashes, ProviderName: stamp.ProviderName, BootstrapIPs: stamp.BootstrapIPs}, nil
+	case StampProtoTypeDoQ:
+		return DoQStamp{Properties: stamp.Props, ServerAddrStr: stamp.ServerAddrStr, Hashes: stamp.Hashes, ProviderName: stamp.ProviderName, BootstrapIPs: stamp.BootstrapIPs}, nil
+	case StampProtoTypeODoHTarget:
+		return ODoHTargetStamp{Properties: stamp.Props, ProviderName: stamp.ProviderName, Path: stamp.Path}, nil
+	case StampProtoTypeDNSCryptRelay:
+		return DNSCryptRelayStamp{ServerAddrStr: stamp.ServerAddrStr}, nil
+	default:
+		return ODoHRelayStamp{Properties: stamp.Props, ServerAddrStr: stamp.ServerAddrStr, Hashes: stamp.Hashes, ProviderName: stamp.ProviderName, Path: stamp.Path, BootstrapIPs: stamp.BootstrapIPs}, nil
+	}
+}
+
+func (s PlainStamp) Proto() StampProtoType {
+	return StampProtoTypePlain
+}
+
+func (s PlainStamp) Props() ServerInformalProperties {
+	return s.Properties
+}
+
+func (s DNSCryptStamp) Proto() StampProtoType {
+	return StampProtoTypeDNSCrypt
+}
+
+func (s DNSCryptStamp) Props() ServerInformalProperties {
+	return s.Properties
+}
+
+func (s DoHStamp) Proto() StampProtoType {
+	return StampProtoTypeDoH
+}
+
+func (s DoHStamp) Props() ServerInformalProperties {
+	return s.Properties
+}
+
+func (s DoTStamp) Proto() StampProtoType {
+	return StampProtoTypeTLS
+}
+
+func (s DoTStamp) Props() ServerInformalProperties {
+	return s.Properties
+}
+
+func (s DoQStamp) Proto() StampProtoType {
+	return StampProtoTypeDoQ
+}
+
+func (s DoQStamp) Props() ServerInformalProperties {
+	return s.Properties
+}
+
+func (s ODoHTargetStamp) Proto() StampProtoType {
+	return StampProtoTypeODoHTarget
+}
+
+func (s ODoHTargetStamp) Props() ServerInformalProperties {
+	return s.Properties
+}
+
+func (s DNSCryptRelayStamp) Proto() StampProtoType {
+	return StampProtoTypeDNSCryptRelay
+}
+
+func (s DNSCryptRelayStamp) Props() ServerInformalProperties {
+	return 0
+}
+
+func (s ODoHRelayStamp) Proto() StampProtoType {
+	return StampProtoTypeODoHRelay
+}
+
+func (s ODoHRelayStamp) Props() ServerInformalProperties {
+	return s.Properties
+}
+
+func (s UnknownStamp) Proto() StampProtoType {
+	return s.Protocol
+}
+
+func (s UnknownStamp) Props() ServerInformalProperties {
+	return 0
+}
+
+func (s PlainStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypePlain, Props: s.Properties, ServerAddrStr: s.ServerAddrStr}
+}
+
+func (s DNSCryptStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypeDNSCrypt, Props: s.Properties, ServerAddrStr: s.ServerAddrStr, ServerPk: s.ServerPk, ProviderName: s.ProviderName}
+}
+
+func (s DoHStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypeDoH, Props: s.Properties, ServerAddrStr: s.ServerAddrStr, Hashes: s.Hashes, ProviderName: s.ProviderName, Path: s.Path, BootstrapIPs: s.BootstrapIPs}
+}
+
+func (s DoTStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypeTLS, Props: s.Properties, ServerAddrStr: s.ServerAddrStr, Hashes: s.Hashes, ProviderName: s.ProviderName, BootstrapIPs: s.BootstrapIPs}
+}
+
+func (s DoQStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypeDoQ, Props: s.Properties, ServerAddrStr: s.ServerAddrStr, Hashes: s.Hashes, ProviderName: s.ProviderName, BootstrapIPs: s.BootstrapIPs}
+}
+
+func (s ODoHTargetStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypeODoHTarget, Props: s.Properties, ProviderName: s.ProviderName, Path: s.Path}
+}
+
+func (s DNSCryptRelayStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypeDNSCryptRelay, ServerAddrStr: s.ServerAddrStr}
+}
+
+func (s ODoHRelayStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: StampProtoTypeODoHRelay, Props: s.Properties, ServerAddrStr: s.ServerAddrStr, Hashes: s.Hashes, ProviderName: s.ProviderName, Path: s.Path, BootstrapIPs: s.BootstrapIPs}
+}
+
+func (s UnknownStamp) ServerStamp() ServerStamp {
+	return ServerStamp{Proto: s.Protocol, Payload: s.Payload}
+}
+
+func (s PlainStamp) String() string {
+	return stampString(s)
+}
+
+func (s DNSCryptStamp) String() string {
+	return stampString(s)
+}
+
+func (s DoHStamp) String() string {
+	return stampString(s)
+}
+
+func (s DoTStamp) String() string {
+	return stampString(s)
+}
+
+func (s DoQStamp) String() string {
+	return stampString(s)
+}
+
+func (s ODoHTargetStamp) String() string {
+	return stampString(s)
+}
+
+func (s DNSCryptRelayStamp) String() string {
+	return stampString(s)
+}
+
+func (s ODoHRelayStamp) String() string {
+	return stampString(s)
+}
+
+func (s UnknownStamp) String() string {
+	return stampString(s)
+}
+
+func stampString(s Stamp) string {
+	stamp := s.ServerStamp()
+	return stamp.String()
+}
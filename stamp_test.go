@@ -0,0 +1,80 @@
+package dnsstamps
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseStamp_ConcreteTypes(t *testing.T) {
+	tests := []struct {
+		stampStr string
+		expected interface{}
+	}{
+		{`sdns://AAcAAAAAAAAABzguOC44Ljg`, PlainStamp{}},
+		{`sdns://AQcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5BkyLmRuc2NyeXB0LWNlcnQubG9jYWxob3N0`, DNSCryptStamp{}},
+		{`sdns://AgcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5AtleGFtcGxlLmNvbQovZG5zLXF1ZXJ5`, DoHStamp{}},
+		{`sdns://AwcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5A9kbnMuZXhhbXBsZS5jb20`, DoTStamp{}},
+		{`sdns://BQcAAAAAAAAAEG9kb2guZXhhbXBsZS5jb20HL3RhcmdldA`, ODoHTargetStamp{}},
+		{`sdns://hQcAAAAAAAAAB1s6OjFdOjEgw4Rr8kuek8pkJ0wOxnwezF4CT_ys0tdAGTUOgf5UauQPZG9oLmV4YW1wbGUuY29tBi9yZWxheQ`, ODoHRelayStamp{}},
+	}
+	for _, test := range tests {
+		stamp, err := ParseStamp(test.stampStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.TypeOf(stamp) != reflect.TypeOf(test.expected) {
+			t.Errorf("expected %T but got %T", test.expected, stamp)
+		}
+		if stamp.String() != test.stampStr {
+			t.Errorf("expected %q but got %q", test.stampStr, stamp.String())
+		}
+		if stamp.Props() != ServerInformalPropertyDNSSEC|ServerInformalPropertyNoLog|ServerInformalPropertyNoFilter {
+			t.Errorf("unexpected props %v", stamp.Props())
+		}
+
+		serverStamp, err := NewServerStampFromString(test.stampStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stamp.Proto() != serverStamp.Proto {
+			t.Errorf("expected proto %v but got %v", serverStamp.Proto, stamp.Proto())
+		}
+		if converted := stamp.ServerStamp(); !reflect.DeepEqual(converted, serverStamp) {
+			t.Errorf("expected %+v but got %+v", serverStamp, converted)
+		}
+	}
+}
+
+func TestFromServerStamp(t *testing.T) {
+	serverStamp := ServerStamp{
+		Proto:         StampProtoTypeDoQ,
+		ServerAddrStr: "1.1.1.1:853",
+		ProviderName:  "cloudflare-dns.com",
+		Hashes:        [][]uint8{pk1},
+		BootstrapIPs:  []string{"1.0.0.1"},
+	}
+	stamp, err := FromServerStamp(serverStamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doq, ok := stamp.(DoQStamp)
+	if !ok {
+		t.Fatalf("expected a DoQStamp, got %T", stamp)
+	}
+	if doq.ProviderName != "cloudflare-dns.com" || len(doq.BootstrapIPs) != 1 {
+		t.Errorf("unexpected stamp %+v", doq)
+	}
+
+	serverStamp.Path = "/dns-query"
+	if _, err := FromServerStamp(serverStamp); !errors.Is(err, ErrUnexpectedField) {
+		t.Errorf("expected ErrUnexpectedField for a DoQ path, got %v", err)
+	}
+	relay := ServerStamp{Proto: StampProtoTypeDNSCryptRelay, ServerAddrStr: "1.1.1.1", Props: ServerInformalPropertyNoLog}
+	if _, err := FromServerStamp(relay); !errors.Is(err, ErrUnexpectedField) {
+		t.Errorf("expected ErrUnexpectedField for relay props, got %v", err)
+	}
+	if _, err := FromServerStamp(ServerStamp{Proto: 0x42}); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("expected ErrUnsupportedProtocol, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package dnsstamps
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+)
+
+// Error kinds reported by TLSConfig() and by the connections it configures.
+var (
+	ErrNoTLS            = errors.New("Protocol doesn't use TLS")
+	ErrCertHashMismatch = errors.New("No certificate matches the hashes of the stamp")
+)
+
+// TLSConfig returns a TLS configuration to connect to the server of a DoH,
+// DoT, DoQ or ODoH stamp. base, that can be nil, is cloned, and its server
+// name and ALPN protocols are kept if they are set. Otherwise, the server
+// name is the provider name without its port, and the ALPN protocols are
+// "h2" and "http/1.1" for DoH and ODoH, "dot" for DoT and "doq" for DoQ.
+//
+// If the stamp has hashes, the connection also requires a certificate of the
+// verified chains to match one of them. If InsecureSkipVerify is set, the
+// chain sent by the server is used instead: the matching certificate must
+// be the leaf, or be linked to it by a chain of signatures. A
+// VerifyConnection callback of base is still called after this check.
+func (stamp *ServerStamp) TLSConfig(base *tls.Config) (*tls.Config, error) {
+	var nextProtos []string
+	switch stamp.Proto {
+	case StampProtoTypeDoH, StampProtoTypeODoHTarget, StampProtoTypeODoHRelay:
+		nextProtos = []string{"h2", "http/1.1"}
+	case StampProtoTypeTLS:
+		nextProtos = []string{"dot"}
+	case StampProtoTypeDoQ:
+		nextProtos = []string{"doq"}
+	default:
+		return nil, &FieldError{Proto: stamp.Proto, Field: FieldProto, Err: ErrNoTLS}
+	}
+
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = stamp.Host()
+	}
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = nextProtos
+	}
+	if len(stamp.Hashes) == 0 {
+		return config, nil
+	}
+
+	pinned := ServerStamp{Proto: stamp.Proto, Hashes: make([][]uint8, 0, len(stamp.Hashes))}
+	for _, hash := range stamp.Hashes {
+		pinned.Hashes = append(pinned.Hashes, append([]uint8{}, hash...))
+	}
+	verifyConnection := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if !pinned.matchesConnection(&cs) {
+			return &FieldError{Proto: pinned.Proto, Field: FieldHash, Err: ErrCertHashMismatch}
+		}
+		if verifyConnection != nil {
+			return verifyConnection(cs)
+		}
+		return nil
+	}
+	return config, nil
+}
+
+// matchesConnection returns true if a certificate of a verified chain
+// matches a hash of the stamp. Without PKI verification, the certificates
+// sent by the server are used instead, but only up to the first one that
+// matches a hash, and only if each of them is signed by the next one, so
+// that a pinned intermediate cannot be sent along with a forged leaf.
+func (stamp *ServerStamp) matchesConnection(cs *tls.ConnectionState) bool {
+	if len(cs.VerifiedChains) > 0 {
+		for _, chain := range cs.VerifiedChains {
+			if _, ok := stamp.MatchesChain(chain); ok {
+				return true
+			}
+		}
+		return false
+	}
+	chain := cs.PeerCertificates
+	for i, cert := range chain {
+		if _, ok := stamp.MatchesChain([]*x509.Certificate{cert}); ok {
+			return true
+		}
+		if i+1 >= len(chain) || cert.CheckSignatureFrom(chain[i+1]) != nil {
+			return false
+		}
+	}
+	return false
+}
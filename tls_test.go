@@ -0,0 +1,109 @@
+package dnsstamps
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTLSConfig(t *testing.T) {
+	tests := []struct {
+		stamp      ServerStamp
+		serverName string
+		nextProtos []string
+	}{
+		{ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "doh.example.com:8443"}, "doh.example.com", []string{"h2", "http/1.1"}},
+		{ServerStamp{Proto: StampProtoTypeODoHTarget, ProviderName: "odoh.example.com"}, "odoh.example.com", []string{"h2", "http/1.1"}},
+		{ServerStamp{Proto: StampProtoTypeTLS, ProviderName: "dot.example.com"}, "dot.example.com", []string{"dot"}},
+		{ServerStamp{Proto: StampProtoTypeDoQ, ProviderName: "[2001:db8::1]:853"}, "2001:db8::1", []string{"doq"}},
+	}
+	for _, test := range tests {
+		config, err := test.stamp.TLSConfig(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.ServerName != test.serverName || !reflect.DeepEqual(config.NextProtos, test.nextProtos) {
+			t.Errorf("%v: unexpected server name %q and ALPN %v", test.stamp.Proto, config.ServerName, config.NextProtos)
+		}
+		if config.VerifyConnection != nil {
+			t.Errorf("%v: unexpected verification callback without hashes", test.stamp.Proto)
+		}
+	}
+
+	base := &tls.Config{ServerName: "override.example.com", NextProtos: []string{"h3"}}
+	stamp := ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "doh.example.com"}
+	config, err := stamp.TLSConfig(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config == base || config.ServerName != "override.example.com" || !reflect.DeepEqual(config.NextProtos, []string{"h3"}) {
+		t.Errorf("the base configuration was not cloned and kept: %+v", config)
+	}
+
+	stamp = ServerStamp{Proto: StampProtoTypeDNSCrypt}
+	if _, err := stamp.TLSConfig(nil); !errors.Is(err, ErrNoTLS) {
+		t.Errorf("expected %v, got %v", ErrNoTLS, err)
+	}
+}
+
+func TestTLSConfig_Pinning(t *testing.T) {
+	chain := testCertChain(t, "doh.example.com")
+	baseCalled := false
+	base := &tls.Config{VerifyConnection: func(tls.ConnectionState) error {
+		baseCalled = true
+		return nil
+	}}
+	stamp := ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "doh.example.com", Hashes: [][]uint8{CertHash(chain[1])}}
+	config, err := stamp.TLSConfig(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.VerifyConnection(tls.ConnectionState{PeerCertificates: chain[:1]}); !errors.Is(err, ErrCertHashMismatch) {
+		t.Errorf("expected %v, got %v", ErrCertHashMismatch, err)
+	}
+	if baseCalled {
+		t.Error("the base callback was called after a mismatch")
+	}
+	if err := config.VerifyConnection(tls.ConnectionState{PeerCertificates: chain}); err != nil {
+		t.Error(err)
+	}
+	if err := config.VerifyConnection(tls.ConnectionState{PeerCertificates: chain[:1], VerifiedChains: [][]*x509.Certificate{chain}}); err != nil {
+		t.Error(err)
+	}
+	if !baseCalled {
+		t.Error("the base callback was not called")
+	}
+}
+
+func TestTLSConfig_PinningForgedLeaf(t *testing.T) {
+	chain := testCertChain(t, "doh.example.com")
+	forged := testCertChain(t, "doh.example.com")
+	stamp := ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "doh.example.com", Hashes: [][]uint8{CertHash(chain[1])}}
+	config, err := stamp.TLSConfig(&tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := tls.ConnectionState{PeerCertificates: []*x509.Certificate{forged[0], chain[1]}}
+	if err := config.VerifyConnection(cs); !errors.Is(err, ErrCertHashMismatch) {
+		t.Errorf("a forged leaf sent with the pinned intermediate was accepted: %v", err)
+	}
+	cs = tls.ConnectionState{PeerCertificates: []*x509.Certificate{forged[1], chain[1]}}
+	if err := config.VerifyConnection(cs); !errors.Is(err, ErrCertHashMismatch) {
+		t.Errorf("a forged self-signed leaf sent with the pinned intermediate was accepted: %v", err)
+	}
+	if err := config.VerifyConnection(tls.ConnectionState{PeerCertificates: chain}); err != nil {
+		t.Errorf("a leaf signed by the pinned intermediate was rejected: %v", err)
+	}
+
+	stamp.Hashes = [][]uint8{CertHash(forged[0])}
+	if config, err = stamp.TLSConfig(&tls.Config{InsecureSkipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.VerifyConnection(tls.ConnectionState{PeerCertificates: forged[:1]}); err != nil {
+		t.Errorf("a pinned leaf was rejected: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package dnsstamps
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// URL returns the upstream URL of the server, in the form accepted by
+// NewServerStampFromURL: "https://host[:port]/path" for DoH,
+// "tls://host[:port]" for DoT, "quic://host[:port]" for DoQ and
+// "udp://ip[:port]" for plain DNS. Default ports are omitted. The host is
+// the provider name, or the IP address of the server if the stamp has no
+// provider name.
+//
+// The other protocols have no URL form, and their "sdns://" form is
+// returned instead.
+//
+// The names of the fields that the URL cannot represent, such as hashes,
+// properties, bootstrap IP addresses, the IP address of a server that also
+// has a host name or a server port that differs from the port of the
+// provider name, are returned as dropped. Bootstrap IP addresses are
+// the addresses of resolvers for the host name, not of the server, so they
+// cannot be used as the host of the URL. See PinnedURL() for the IP address
+// of the server.
+func (stamp *ServerStamp) URL() (string, []string, error) {
+	return stamp.upstreamURL(false)
+}
+
+// PinnedURL returns the upstream URL of the server like URL(), except that
+// the host of DoH, DoT and DoQ URLs is always the IP address of the server,
+// so that no name resolution is needed. If the server address has no port,
+// the port of the provider name is used. A URL cannot hold a separate TLS
+// server name: if the provider name is a host name, it is returned as
+// dropped, and it must be configured separately, for example with
+// TLSConfig().
+func (stamp *ServerStamp) PinnedURL() (string, []string, error) {
+	return stamp.upstreamURL(true)
+}
+
+func (stamp *ServerStamp) upstreamURL(pinned bool) (string, []string, error) {
+	if err := stamp.checkEncodable(); err != nil {
+		return "", nil, err
+	}
+	var scheme string
+	switch stamp.Proto {
+	case StampProtoTypeDoH:
+		scheme = "https"
+	case StampProtoTypeTLS:
+		scheme = "tls"
+	case StampProtoTypeDoQ:
+		scheme = "quic"
+	case StampProtoTypePlain:
+		scheme = "udp"
+	default:
+		stampStr, err := stamp.Encode()
+		return stampStr, nil, err
+	}
+
+	var dropped []string
+	hostPort := stamp.ProviderName
+	switch {
+	case stamp.Proto == StampProtoTypePlain:
+		hostPort = stamp.ServerAddrStr
+	case pinned || hostPort == "":
+		addrPort, err := stamp.AddrPort()
+		if err != nil {
+			return "", nil, err
+		}
+		if portIndex(stamp.ServerAddrStr) < 0 {
+			port, err := stamp.Port()
+			if err != nil {
+				return "", nil, err
+			}
+			addrPort = netip.AddrPortFrom(addrPort.Addr(), port)
+		}
+		hostPort = addrPort.String()
+		if stamp.SNI() != "" {
+			dropped = append(dropped, FieldProviderName)
+		}
+	case stamp.SNI() != "":
+		if host, _ := splitServerAddr(stamp.ServerAddrStr); host != "" {
+			dropped = append(dropped, FieldAddress)
+		}
+	}
+	if stamp.Proto != StampProtoTypePlain && stamp.ProviderName != "" && portIndex(stamp.ServerAddrStr) >= 0 {
+		_, addrPort, err := stamp.splitHostPort(stamp.ServerAddrStr)
+		if err != nil {
+			return "", nil, &FieldError{Proto: stamp.Proto, Field: FieldAddress, Err: err}
+		}
+		port, err := stamp.Port()
+		if err != nil {
+			return "", nil, err
+		}
+		if addrPort != port {
+			dropped = append(dropped, FieldPort)
+		}
+	}
+
+	codec, _ := LookupCodec(stamp.Proto)
+	hostPort = strings.TrimSuffix(hostPort, ":"+strconv.Itoa(codec.DefaultPort))
+	stampURL := scheme + "://" + hostPort
+	if stamp.Proto == StampProtoTypeDoH {
+		stampURL += stamp.Path
+	}
+
+	if len(stamp.Hashes) > 0 {
+		dropped = append(dropped, FieldHash)
+	}
+	if stamp.Props != 0 {
+		dropped = append(dropped, FieldProps)
+	}
+	if len(stamp.BootstrapIPs) > 0 {
+		dropped = append(dropped, FieldBootstrapIP)
+	}
+	return stampURL, dropped, nil
+}
@@ -0,0 +1,127 @@
+package dnsstamps
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		stamp   ServerStamp
+		url     string
+		dropped []string
+	}{
+		{ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com:443", Path: "/dns-query"}, "https://dns.example.com/dns-query", nil},
+		{ServerStamp{Proto: StampProtoTypeDoH, ServerAddrStr: "1.1.1.1:443", ProviderName: "1.1.1.1", Path: "/dns-query"}, "https://1.1.1.1/dns-query", nil},
+		{ServerStamp{Proto: StampProtoTypeDoH, ServerAddrStr: "1.1.1.1", ProviderName: "cloudflare-dns.com", Path: "/dns-query", Hashes: [][]uint8{pk1}, Props: ServerInformalPropertyNoLog},
+			"https://cloudflare-dns.com/dns-query", []string{FieldAddress, FieldHash, FieldProps}},
+		{ServerStamp{Proto: StampProtoTypeTLS, ProviderName: "dns.example.com", BootstrapIPs: []string{"192.0.2.1"}}, "tls://dns.example.com", []string{FieldBootstrapIP}},
+		{ServerStamp{Proto: StampProtoTypeTLS, ServerAddrStr: ":8853", ProviderName: "dot.example"}, "tls://dot.example", []string{FieldPort}},
+		{ServerStamp{Proto: StampProtoTypeDoH, ServerAddrStr: "1.1.1.1:8443", ProviderName: "1.1.1.1", Path: "/dns-query"}, "https://1.1.1.1/dns-query", []string{FieldPort}},
+		{ServerStamp{Proto: StampProtoTypeDoQ, ServerAddrStr: "[2001:db8::1]:8853", ProviderName: "[2001:db8::1]:8853"}, "quic://[2001:db8::1]:8853", nil},
+		{ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: "9.9.9.9:53"}, "udp://9.9.9.9", nil},
+		{ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: "[2001:db8::1]:5353"}, "udp://[2001:db8::1]:5353", nil},
+	}
+	for _, test := range tests {
+		stampURL, dropped, err := test.stamp.URL()
+		if err != nil {
+			t.Errorf("%+v: %v", test.stamp, err)
+			continue
+		}
+		if stampURL != test.url || !reflect.DeepEqual(dropped, test.dropped) {
+			t.Errorf("expected %q dropping %v, got %q dropping %v", test.url, test.dropped, stampURL, dropped)
+		}
+		if len(dropped) > 0 {
+			continue
+		}
+		parsed, err := NewServerStampFromURL(stampURL, 0, nil)
+		if err != nil {
+			t.Errorf("%s: %v", stampURL, err)
+			continue
+		}
+		if !parsed.Equal(&test.stamp) {
+			t.Errorf("%s: parsed as %+v, expected %+v", stampURL, parsed, test.stamp)
+		}
+	}
+}
+
+func TestURL_StampFallback(t *testing.T) {
+	stamp := ServerStamp{Proto: StampProtoTypeODoHTarget, ProviderName: "odoh.example.com", Path: "/dns-query"}
+	stampURL, dropped, err := stamp.URL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stampURL, StampScheme) || stampURL != stamp.String() || dropped != nil {
+		t.Errorf("unexpected URL %q dropping %v", stampURL, dropped)
+	}
+
+	stamp = ServerStamp{Proto: 0x42}
+	if _, _, err := stamp.URL(); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedProtocol, err)
+	}
+}
+
+func TestURL_ParsedStamps(t *testing.T) {
+	tests := []struct {
+		stamp ServerStamp
+		url   string
+	}{
+		{ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com"}, "https://dns.example.com"},
+		{ServerStamp{Proto: StampProtoTypeDoH, ServerAddrStr: "1.1.1.1:443", Path: "/dns-query"}, "https://1.1.1.1/dns-query"},
+		{ServerStamp{Proto: StampProtoTypeTLS, ServerAddrStr: "[2001:db8::1]:8853"}, "tls://[2001:db8::1]:8853"},
+	}
+	for _, test := range tests {
+		parsedStamp, err := NewServerStampFromString(test.stamp.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		stampURL, _, err := parsedStamp.URL()
+		if err != nil {
+			t.Errorf("%+v: %v", test.stamp, err)
+			continue
+		}
+		if stampURL != test.url {
+			t.Errorf("expected %q, got %q", test.url, stampURL)
+		}
+	}
+
+	stamp := ServerStamp{Proto: StampProtoTypeTLS, ServerAddrStr: ":8853"}
+	if _, _, err := stamp.URL(); !errors.Is(err, ErrMissingField) {
+		t.Errorf("expected %v, got %v", ErrMissingField, err)
+	}
+}
+
+func TestPinnedURL(t *testing.T) {
+	tests := []struct {
+		stamp   ServerStamp
+		url     string
+		dropped []string
+	}{
+		{ServerStamp{Proto: StampProtoTypeDoH, ServerAddrStr: "1.1.1.1", ProviderName: "cloudflare-dns.com", Path: "/dns-query"},
+			"https://1.1.1.1/dns-query", []string{FieldProviderName}},
+		{ServerStamp{Proto: StampProtoTypeTLS, ServerAddrStr: "[2001:db8::1]:8853", ProviderName: "dns.example.com:8853", BootstrapIPs: []string{"192.0.2.1"}},
+			"tls://[2001:db8::1]:8853", []string{FieldProviderName, FieldBootstrapIP}},
+		{ServerStamp{Proto: StampProtoTypeDoQ, ServerAddrStr: "192.0.2.1", ProviderName: "192.0.2.1"}, "quic://192.0.2.1", nil},
+		{ServerStamp{Proto: StampProtoTypeDoH, ServerAddrStr: "1.2.3.4", ProviderName: "dns.example:8443", Path: "/dns-query"},
+			"https://1.2.3.4:8443/dns-query", []string{FieldProviderName}},
+		{ServerStamp{Proto: StampProtoTypeDoQ, ServerAddrStr: "192.0.2.1:853", ProviderName: "192.0.2.1:8853"}, "quic://192.0.2.1", []string{FieldPort}},
+		{ServerStamp{Proto: StampProtoTypePlain, ServerAddrStr: "9.9.9.9"}, "udp://9.9.9.9", nil},
+	}
+	for _, test := range tests {
+		stampURL, dropped, err := test.stamp.PinnedURL()
+		if err != nil {
+			t.Errorf("%+v: %v", test.stamp, err)
+			continue
+		}
+		if stampURL != test.url || !reflect.DeepEqual(dropped, test.dropped) {
+			t.Errorf("expected %q dropping %v, got %q dropping %v", test.url, test.dropped, stampURL, dropped)
+		}
+	}
+
+	stamp := ServerStamp{Proto: StampProtoTypeDoH, ProviderName: "dns.example.com", Path: "/dns-query"}
+	if _, _, err := stamp.PinnedURL(); !errors.Is(err, ErrMissingField) {
+		t.Errorf("expected %v for a stamp without an IP address, got %v", ErrMissingField, err)
+	}
+}
@@ -0,0 +1,200 @@
+package dnsstamps
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Error kinds reported by Validate(), in addition to the encoding errors.
+var (
+	ErrMissingField    = errors.New("Field is required by the protocol")
+	ErrPublicKeyLength = errors.New("Invalid public key (must be 32 bytes)")
+	ErrInvalidPath     = errors.New("Invalid path (must be an absolute URI path)")
+)
+
+// Validate checks that the stamp can be encoded, and that it follows the
+// rules of its protocol: addresses and ports are valid, hashes and DNSCrypt
+// public keys are 32 bytes long, the provider name and the path are set
+// where the protocol requires them, and bootstrap IP addresses are unique IP
+// literals. Every problem is reported as a *FieldError, and the problems are
+// combined with errors.Join.
+//
+// Validate is stricter than the parser and than Encode(): a stamp decoded
+// by NewServerStampFromString, such as a DoH stamp without a path or a stamp
+// decoded with ParseOptions.LenientBootstrapIPs, can still be reported.
+//
+// For protocols registered with RegisterCodec, the codec's Validate function
+// is used, if any.
+func (stamp *ServerStamp) Validate() error {
+	codec, ok := LookupCodec(stamp.Proto)
+	if !ok {
+		return stamp.checkEncodable()
+	}
+	if codec.Validate == nil {
+		return nil
+	}
+	return codec.Validate(stamp)
+}
+
+// checkEncodable checks that the stamp can be encoded without losing or
+// truncating fields: the protocol is supported, or the stamp has a payload,
+// the stamp has no fields that its protocol doesn't encode, the server
+// address is valid, hashes and DNSCrypt public keys are 32 bytes long and no
+// field is too long. Fields of protocols registered
+// with RegisterCodec are left to the codec's Encode function.
+func (stamp *ServerStamp) checkEncodable() error {
+	if !stamp.Proto.supported() {
+		if stamp.Payload == nil {
+			return &FieldError{Proto: stamp.Proto, Field: FieldProto, Err: ErrUnsupportedProtocol}
+		}
+		var errs []error
+		for _, field := range stamp.unexpectedFields() {
+			errs = append(errs, &FieldError{Proto: stamp.Proto, Field: field, Err: ErrUnexpectedField})
+		}
+		return errors.Join(errs...)
+	}
+	if !stamp.Proto.builtin() {
+		return nil
+	}
+	return errors.Join(encodingErrors(stamp)...)
+}
+
+// encodingErrors returns the problems that prevent a stamp of one of the
+// built-in protocols from being encoded.
+func encodingErrors(stamp *ServerStamp) []error {
+	fields, defaultPort, allowEmptyIP := stampFields(stamp.Proto)
+	var errs []error
+	fieldErr := func(field string, err error) {
+		errs = append(errs, &FieldError{Proto: stamp.Proto, Field: field, Err: err})
+	}
+
+	for _, field := range stamp.unexpectedFields() {
+		fieldErr(field, ErrUnexpectedField)
+	}
+
+	if fields&stampFieldAddr != 0 {
+		optional := stamp.Proto != StampProtoTypePlain && stamp.Proto != StampProtoTypeDNSCrypt &&
+			stamp.Proto != StampProtoTypeDNSCryptRelay
+		if stamp.ServerAddrStr != "" || !optional {
+			addr := ServerStamp{Proto: stamp.Proto, ServerAddrStr: stamp.ServerAddrStr}
+			if err := parseServerAddr(&addr, -1, defaultPort, allowEmptyIP); err != nil {
+				fieldErr(FieldAddress, err.(*ParseError).Kind)
+			}
+		}
+	}
+	if fields&stampFieldPk != 0 && len(stamp.ServerPk) != 32 {
+		fieldErr(FieldPublicKey, ErrPublicKeyLength)
+	}
+	if fields&stampFieldHashes != 0 {
+		for _, hash := range stamp.Hashes {
+			if len(hash) != 32 {
+				fieldErr(FieldHash, ErrHashLength)
+			}
+		}
+	}
+	return append(errs, fieldLengthErrors(stamp)...)
+}
+
+// fieldLengthErrors returns the fields of a stamp of one of the built-in
+// protocols that are too long for their length prefix.
+func fieldLengthErrors(stamp *ServerStamp) []error {
+	fields, defaultPort, _ := stampFields(stamp.Proto)
+	var errs []error
+	fieldErr := func(field string, err error) {
+		errs = append(errs, &FieldError{Proto: stamp.Proto, Field: field, Err: err})
+	}
+
+	if fields&stampFieldAddr != 0 {
+		serverAddrStr := strings.TrimSuffix(stamp.ServerAddrStr, ":"+strconv.Itoa(defaultPort))
+		if len(serverAddrStr) > 0xff {
+			fieldErr(FieldAddress, ErrFieldTooLong)
+		}
+	}
+	if len(stamp.ServerPk) > 0xff {
+		fieldErr(FieldPublicKey, ErrFieldTooLong)
+	}
+	for _, hash := range stamp.Hashes {
+		if len(hash) > 0x7f {
+			fieldErr(FieldHash, ErrFieldTooLong)
+		}
+	}
+	if len(stamp.ProviderName) > 0xff {
+		fieldErr(FieldProviderName, ErrFieldTooLong)
+	}
+	if len(stamp.Path) > 0xff {
+		fieldErr(FieldPath, ErrFieldTooLong)
+	}
+	for _, bootstrapIP := range stamp.BootstrapIPs {
+		if len(bootstrapIP) > 0x7f {
+			fieldErr(FieldBootstrapIP, ErrFieldTooLong)
+		}
+	}
+	return errs
+}
+
+// validateFields checks a stamp of one of the built-in protocols.
+func validateFields(stamp *ServerStamp) error {
+	fields, _, _ := stampFields(stamp.Proto)
+	errs := encodingErrors(stamp)
+	fieldErr := func(field string, err error) {
+		errs = append(errs, &FieldError{Proto: stamp.Proto, Field: field, Err: err})
+	}
+
+	if fields&stampFieldProviderName != 0 && stamp.ProviderName == "" {
+		fieldErr(FieldProviderName, ErrMissingField)
+	}
+	if fields&stampFieldPath != 0 && !validPath(stamp.Path) {
+		fieldErr(FieldPath, ErrInvalidPath)
+	}
+	seen := make(map[string]bool)
+	for _, bootstrapIP := range stamp.BootstrapIPs {
+		normalized, err := normalizeBootstrapIP(bootstrapIP)
+		if err != nil {
+			fieldErr(FieldBootstrapIP, ErrInvalidBootstrapIP)
+		} else if seen[normalized] {
+			fieldErr(FieldBootstrapIP, ErrDuplicateBootstrapIP)
+		}
+		seen[normalized] = true
+	}
+	return errors.Join(errs...)
+}
+
+func validPath(path string) bool {
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+	for _, c := range path {
+		if c <= ' ' || c == 0x7f || c == '#' {
+			return false
+		}
+	}
+	return true
+}
+
+// unexpectedFields returns the names of the non-empty fields that the
+// protocol of the stamp doesn't encode.
+func (stamp *ServerStamp) unexpectedFields() []string {
+	fields, _, _ := stampFields(stamp.Proto)
+	present := []struct {
+		field   int
+		name    string
+		present bool
+	}{
+		{stampFieldProps, FieldProps, stamp.Props != 0},
+		{stampFieldAddr, FieldAddress, stamp.ServerAddrStr != ""},
+		{stampFieldPk, FieldPublicKey, len(stamp.ServerPk) > 0},
+		{stampFieldHashes, FieldHash, len(stamp.Hashes) > 0},
+		{stampFieldProviderName, FieldProviderName, stamp.ProviderName != ""},
+		{stampFieldPath, FieldPath, stamp.Path != ""},
+		{stampFieldBootstrapIPs, FieldBootstrapIP, len(stamp.BootstrapIPs) > 0},
+		{stampFieldPayload, FieldPayload, stamp.Payload != nil},
+	}
+	var names []string
+	for _, p := range present {
+		if p.present && fields&p.field == 0 {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+package dnsstamps
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate_Valid(t *testing.T) {
+	stamps := []string{
+		`sdns://AQcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5BkyLmRuc2NyeXB0LWNlcnQubG9jYWxob3N0`,
+		`sdns://AgcAAAAAAAAACTEyNy4wLjAuMSDDhGvyS56TymQnTA7GfB7MXgJP_KzS10AZNQ6B_lRq5AtleGFtcGxlLmNvbQovZG5zLXF1ZXJ5`,
+		`sdns://BQcAAAAAAAAAEG9kb2guZXhhbXBsZS5jb20HL3RhcmdldA`,
+		`sdns://hQcAAAAAAAAAB1s6OjFdOjEgw4Rr8kuek8pkJ0wOxnwezF4CT_ys0tdAGTUOgf5UauQPZG9oLmV4YW1wbGUuY29tBi9yZWxheQ`,
+		`sdns://AAcAAAAAAAAADDguOC44Ljg6ODA1Mw`,
+	}
+	for _, stampStr := range stamps {
+		stamp, err := NewServerStampFromString(stampStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stamp.Validate(); err != nil {
+			t.Errorf("%s: unexpected error: %v", stampStr, err)
+		}
+	}
+}
+
+func TestValidate_AllProblems(t *testing.T) {
+	stamp := ServerStamp{
+		Proto:         StampProtoTypeDNSCrypt,
+		ServerAddrStr: "127.0.0.1:0",
+		ServerPk:      []uint8{1, 2, 3},
+		Path:          "/dns-query",
+	}
+	err := stamp.Validate()
+	for _, expected := range []error{ErrPortRange, ErrPublicKeyLength, ErrMissingField, ErrUnexpectedField} {
+		if !errors.Is(err, expected) {
+			t.Errorf("expected %v to be reported, got %v", expected, err)
+		}
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %T", err)
+	}
+	if n := len(joined.Unwrap()); n != 4 {
+		t.Errorf("expected 4 problems but got %d: %v", n, err)
+	}
+}
+
+func TestValidate_Path(t *testing.T) {
+	stamp := ServerStamp{
+		Proto:         StampProtoTypeDoH,
+		ServerAddrStr: "1.1.1.1",
+		ProviderName:  "cloudflare-dns.com",
+		Path:          "dns-query",
+	}
+	if err := stamp.Validate(); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath for a relative path, got %v", err)
+	}
+	stamp.Path = "/dns query"
+	if err := stamp.Validate(); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath for a path with a space, got %v", err)
+	}
+	stamp.Path = "/dns-query"
+	if err := stamp.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var fieldErr *FieldError
+	stamp.ProviderName = ""
+	if err := stamp.Validate(); !errors.As(err, &fieldErr) || fieldErr.Field != FieldProviderName {
+		t.Errorf("expected an error on the provider name, got %v", err)
+	}
+}